@@ -3,10 +3,13 @@ package cli
 import (
 	"fmt"
 	"os"
+	"os/exec"
 	"os/signal"
 	"path/filepath"
+	"runtime"
 	"syscall"
 
+	"github.com/influx6/relay/engine"
 	"github.com/spf13/cobra"
 )
 
@@ -250,6 +253,63 @@ var serveCommand = &cobra.Command{
 	},
 }
 
+// RoutesCommand prints the routes of the relay project
+var routesCommand = &cobra.Command{
+	Use:   "routes",
+	Short: "builds the current relay project and prints its registered routes",
+	Long:  `routes builds the project binary using the package in 'app.yml' and runs it in a mode where the engine prints the methods, pattern, host, name and handler of every registered route instead of serving`,
+	Run: func(cmd *cobra.Command, args []string) {
+		pwd, _ := os.Getwd()
+
+		fmt.Printf("--> Searching for 'app.yaml' file in (%s)....\n", pwd)
+
+		//get the app.file
+		appfile := filepath.Join(pwd, "./app.yml")
+
+		if _, err := os.Stat(appfile); err != nil {
+			fmt.Printf("--> --> Error: 'app.yml' not found in (%s)....\n", pwd)
+			return
+		}
+
+		var config = NewBuildConfig()
+		fmt.Printf("--> Found app.yml and loading into config...\n")
+
+		if err := config.Load(appfile); err != nil {
+			fmt.Printf("--> --> ConfigError: 'app.yaml' -> %s\n", err)
+			return
+		}
+
+		_, binName := filepath.Split(config.Package)
+		bin := filepath.Join(os.TempDir(), fmt.Sprintf("%s-routes", binName))
+
+		if runtime.GOOS == "windows" {
+			bin = bin + ".exe"
+		}
+
+		fmt.Printf("--> Building '%s'...\n", config.Package)
+
+		build := exec.Command("go", "build", "-o", bin, config.Package)
+		build.Stdout = os.Stdout
+		build.Stderr = os.Stderr
+
+		if err := build.Run(); err != nil {
+			fmt.Printf("--> --> BuildError: '%s' -> %s\n", config.Package, err)
+			return
+		}
+
+		defer os.Remove(bin)
+
+		list := exec.Command(bin, config.BinArgs...)
+		list.Env = append(os.Environ(), engine.RoutesEnv+"=1")
+		list.Stdout = os.Stdout
+		list.Stderr = os.Stderr
+
+		if err := list.Run(); err != nil {
+			fmt.Printf("--> --> RoutesError: '%s' -> %s\n", binName, err)
+		}
+	},
+}
+
 // RootCmd provides the core command for the cli
 var RootCmd = &cobra.Command{
 	Use:   "relay",
@@ -264,5 +324,5 @@ func init() {
 	//add the build command to the server
 	// serveCommand.AddCommand(buildCommand)
 	//loadup all commands to the root command
-	RootCmd.AddCommand(buildCommand, serveCommand, createCommand)
+	RootCmd.AddCommand(buildCommand, serveCommand, createCommand, routesCommand)
 }
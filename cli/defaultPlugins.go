@@ -15,6 +15,7 @@ import (
 	"github.com/influx6/flux"
 	"github.com/influx6/reactors/builders"
 	"github.com/influx6/reactors/fs"
+	"github.com/influx6/relay/relay"
 )
 
 // RegisterDefaultPlugins provides a set of default plugins for relay
@@ -357,6 +358,7 @@ func addGoStaticBundle(pm *PluginManager) {
 						gzipped: true
 						nodecompression: true
 						production: true // generally you want to leave this to the cli to set
+						manifest: ./static/manifest.json // optional, writes the fingerprinted names of the files for relay.AssetPaths
 
 		  		  where the config.path is the path to be watched
 
@@ -371,6 +373,7 @@ func addGoStaticBundle(pm *PluginManager) {
 		packageName := options.Config["package"]
 		fileName := options.Config["file"]
 		ignore := options.Config["ignore"]
+		manifest := options.Config["manifest"]
 		absDir := filepath.Join(pwd, inDir)
 		absFile := filepath.Join(pwd, outDir, fileName+".go")
 
@@ -432,8 +435,26 @@ func addGoStaticBundle(pm *PluginManager) {
 			fmt.Printf("--> goStatic.Reacted: State %t Error: (%+s)\n", data, err)
 		}, true)
 
+		//writes the manifest of fingerprinted names shared with relay.AssetPaths
+		writeManifest := func() {
+			if manifest == "" {
+				return
+			}
+
+			am, err := relay.BuildAssetManifest(absDir)
+			if err != nil {
+				fmt.Printf("---> goStatic.error: unable to build manifest: %s\n", err)
+				return
+			}
+
+			if err := am.WriteFile(filepath.Join(pwd, manifest)); err != nil {
+				fmt.Printf("---> goStatic.error: unable to write manifest: %s\n", err)
+			}
+		}
+
 		//bundle up the assets for the main time
 		gostatic.Send(true)
+		writeManifest()
 
 		var command []string
 
@@ -471,6 +492,11 @@ func addGoStaticBundle(pm *PluginManager) {
 		watcher.React(flux.SimpleMuxer(func(root flux.Reactor, data interface{}) {
 			if ev, ok := data.(fsnotify.Event); ok {
 				fmt.Printf("--> goStatic:File as changed: %+s\n", ev.String())
+
+				//skip the manifest itself when written within the watched directory
+				if filepath.Base(ev.Name) != relay.DefaultManifestFile {
+					writeManifest()
+				}
 			}
 		}), true)
 
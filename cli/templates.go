@@ -29,7 +29,7 @@ func main() {
 
 	server := engine.NewEngine(engine.NewConfig(), func(app *engine.Engine) {
 		
-		app.ServeDir("/static/*", app.Static.Dir, app.Static.StripPrefix)
+		app.ServeStatic("/static/*")
 
 	})
 
@@ -68,6 +68,9 @@ hearbeat: %dm
 # directory and settings for static code
 static:
   dir: ./static
+  # cache-control of the static files by url path prefix
+  # cache_control:
+  #   /static/: public, max-age=3600
 
 # directory to locate client gopherjs code
 client:
@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"strings"
 	"syscall"
 	"time"
@@ -70,6 +71,8 @@ func (t *TLSConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
 type StaticConfig struct {
 	Dir         string `yaml:"dir"`
 	StripPrefix string `yaml:"strip_prefix"`
+	//CacheControl maps url path prefixes to the Cache-Control of the static files served under them
+	CacheControl map[string]string `yaml:"cache_control"`
 }
 
 // Db provides a generic db configuration value
@@ -103,6 +106,10 @@ const DevelopmentMode = 0
 // ProductionMode repesents a config env set to production
 const ProductionMode = 1
 
+// RoutesEnv is the environment variable which when set makes Engine.Serve print
+// the registered routes and exit instead of serving, it is used by 'relay routes'
+const RoutesEnv = "RELAY_ROUTES"
+
 // NewConfig returns a new configuration file
 func NewConfig() *Config {
 	c := DefaultConfig
@@ -151,6 +158,8 @@ type Engine struct {
 	stop      time.Duration
 	heartbeat time.Duration
 	Template  *assets.TemplateDir
+	//Assets provides the content hashed paths of the static files used by the 'asset' and 'assetPath' template functions
+	Assets *relay.AssetPaths
 	//HeartBeats is run a constant rate every ms provided
 	HeartBeats func(*Engine)
 	//BeforeInit is run right before the server is started
@@ -171,12 +180,45 @@ func NewEngine(c *Config, init func(*Engine)) *Engine {
 		OnInit:      init,
 	}
 
+	//templates get the default functions along with the 'url' and 'asset' functions,
+	//these can be replaced through Templates.AddFuncs
+	eo.Templates = relay.NewTemplates(c.TemplatesConfig.Dir, c.TemplatesConfig.Extension, c.Mode == DevelopmentMode)
+	eo.Assets = relay.NewAssetPaths(c.Static.Dir, c.Static.StripPrefix)
+	eo.Templates.AddFuncs(eo.FuncMap()).AddFuncs(eo.Assets.FuncMap())
+
 	eo.stop = makeDuration(c.Killbeat, 20)
 	eo.heartbeat = makeDuration(c.Heartbeat, (10 * 60))
 
 	return eo
 }
 
+// loadTemplates updates the templates and assets with the config, which may have
+// been loaded after the engine was created. Templates are re-parsed on changes in
+// development and cached in production, where the asset manifest of the static
+// directory is used when present
+func (a *Engine) loadTemplates() {
+	a.Templates.Dir = a.TemplatesConfig.Dir
+	a.Templates.Ext = a.TemplatesConfig.Extension
+	a.Templates.Reload = a.Mode == DevelopmentMode
+	a.Assets.Dir = a.Static.Dir
+	a.Assets.Prefix = a.Static.StripPrefix
+
+	if a.Mode == ProductionMode {
+		manifest := filepath.Join(a.Static.Dir, relay.DefaultManifestFile)
+		if err := a.Assets.LoadManifest(manifest); err != nil && !os.IsNotExist(err) {
+			log.Printf("Unable to load asset manifest: %s -> %s", manifest, err.Error())
+		}
+	}
+}
+
+// ServeStatic serves the static directory under the pattern with the Cache-Control
+// policy of the config, see relay.ChainRouter.ServeDir
+func (a *Engine) ServeStatic(pattern string) *relay.FS {
+	fs := a.ServeDir(pattern, a.Static.Dir, a.Static.StripPrefix)
+	fs.CacheControl = relay.CachePolicy(a.Static.CacheControl)
+	return fs
+}
+
 func (a *Engine) loadup() error {
 	if a.OnInit != nil {
 		a.OnInit(a)
@@ -186,6 +228,14 @@ func (a *Engine) loadup() error {
 
 // Serve serves the app and configuration and loads the routes and serivices settings
 func (a *Engine) Serve() {
+	//only list the routes when requested by the relay cli
+	if os.Getenv(RoutesEnv) != "" {
+		if err := a.printRoutes(); err != nil {
+			panic(err)
+		}
+		return
+	}
+
 	//start up the app server calling the .Serve()
 	if err := a.prepareServer(); err != nil {
 		panic(err)
@@ -218,6 +268,13 @@ func (a *Engine) prepareServer() error {
 	var err error
 	var ls net.Listener
 
+	a.loadTemplates()
+
+	//errors returned by handlers are rendered as problem details, with their messages in development
+	if a.ErrorHandler == nil {
+		a.ErrorHandler = relay.ProblemHandler(a.Mode == DevelopmentMode)
+	}
+
 	//run the before init function
 	if a.BeforeInit != nil {
 		a.BeforeInit(a)
@@ -265,6 +322,21 @@ func (a *Engine) prepareServer() error {
 	return nil
 }
 
+// printRoutes runs the init functions without starting the server and prints the registered routes
+func (a *Engine) printRoutes() error {
+	a.loadTemplates()
+
+	if a.BeforeInit != nil {
+		a.BeforeInit(a)
+	}
+
+	if err := a.loadup(); err != nil {
+		return err
+	}
+
+	return a.PrintRoutes(os.Stdout)
+}
+
 // EngineAddr returns the address of the app
 func (a *Engine) EngineAddr() net.Addr {
 	if a.ls == nil {
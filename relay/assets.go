@@ -0,0 +1,215 @@
+package relay
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// assets.go provides fingerprinting of static files by their content hash and the
+// manifest mapping asset names to their fingerprinted names, shared by templates
+// and the build tools
+//
+//   <script src="{{assetPath "js/app.js"}}"></script>
+//   // => <script src="/static/js/app.3a7bd3e2360a.js"></script>
+
+// DefaultManifestFile provides the name of the asset manifest within a static directory
+const DefaultManifestFile = "manifest.json"
+
+// FingerprintSize provides the number of hex characters of the content hash used in fingerprints
+const FingerprintSize = 12
+
+// ImmutableCacheControl provides the Cache-Control of fingerprinted files, which never change
+const ImmutableCacheControl = "public, max-age=31536000, immutable"
+
+// fingerprinted matches names carrying a fingerprint before their extension, i.e 'app.3a7bd3e2360a.js'
+var fingerprinted = regexp.MustCompile(`^(.+)\.([0-9a-f]{6,64})(\.[^./]+)$`)
+
+// AssetManifest maps the names of assets to their fingerprinted names, i.e 'js/app.js' to 'js/app.3a7bd3e2360a.js'
+type AssetManifest map[string]string
+
+// BuildAssetManifest returns an AssetManifest of the files within the directory,
+// hidden files and the manifest file are skipped
+func BuildAssetManifest(dir string) (AssetManifest, error) {
+	manifest := make(AssetManifest)
+
+	err := filepath.Walk(dir, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if strings.HasPrefix(info.Name(), ".") && file != dir {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if info.IsDir() || info.Name() == DefaultManifestFile {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, file)
+		if err != nil {
+			return err
+		}
+
+		fl, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer fl.Close()
+
+		hash, err := contentHash(fl)
+		if err != nil {
+			return err
+		}
+
+		name := filepath.ToSlash(rel)
+		manifest[name] = FingerprintName(name, hash)
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return manifest, nil
+}
+
+// LoadAssetManifest returns the AssetManifest stored in the json file
+func LoadAssetManifest(file string) (AssetManifest, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest AssetManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, err
+	}
+
+	return manifest, nil
+}
+
+// WriteFile stores the manifest as json into the file
+func (m AssetManifest) WriteFile(file string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(file, data, 0644)
+}
+
+// FingerprintName returns the name with the first FingerprintSize characters of
+// the hash placed before its extension, i.e 'app.3a7bd3e2360a.js'
+func FingerprintName(name, hash string) string {
+	if len(hash) > FingerprintSize {
+		hash = hash[:FingerprintSize]
+	}
+
+	ext := path.Ext(name)
+	return strings.TrimSuffix(name, ext) + "." + hash + ext
+}
+
+// SplitFingerprint returns the name without its fingerprint and the fingerprint,
+// it returns false if the name carries no fingerprint
+func SplitFingerprint(name string) (string, string, bool) {
+	parts := fingerprinted.FindStringSubmatch(name)
+	if parts == nil {
+		return name, "", false
+	}
+
+	return parts[1] + parts[3], parts[2], true
+}
+
+// contentHash returns the hex sha256 hash of the content
+func contentHash(r io.Reader) (string, error) {
+	sum := sha256.New()
+	if _, err := io.Copy(sum, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(sum.Sum(nil)), nil
+}
+
+// fileHashes provides a cache of the content hashes of files, keeping each hash
+// until the file's size or modtime changes
+type fileHashes struct {
+	rw    sync.RWMutex
+	items map[string]assetHash
+}
+
+// assetHash provides the content hash of a file and the stat it was computed from
+type assetHash struct {
+	size int64
+	mod  time.Time
+	hash string
+}
+
+// servedDirs provides the hash caches of the directories served through ServeFile
+var servedDirs = struct {
+	sync.Mutex
+	items map[string]*fileHashes
+}{items: make(map[string]*fileHashes)}
+
+// dirHashes returns the hash cache of the directory served through ServeFile
+func dirHashes(dir string) *fileHashes {
+	servedDirs.Lock()
+	defer servedDirs.Unlock()
+
+	hashes, ok := servedDirs.items[dir]
+	if !ok {
+		hashes = newFileHashes()
+		servedDirs.items[dir] = hashes
+	}
+
+	return hashes
+}
+
+// newFileHashes returns a new fileHashes
+func newFileHashes() *fileHashes {
+	return &fileHashes{items: make(map[string]assetHash)}
+}
+
+// hash returns the content hash of the file, reading it through open only when
+// the cached hash is missing or stale. A nil fileHashes computes the hash every time
+func (f *fileHashes) hash(name string, stat os.FileInfo, open func() (io.ReadCloser, error)) (string, error) {
+	if f != nil {
+		f.rw.RLock()
+		cached, ok := f.items[name]
+		f.rw.RUnlock()
+
+		if ok && cached.size == stat.Size() && cached.mod.Equal(stat.ModTime()) {
+			return cached.hash, nil
+		}
+	}
+
+	fl, err := open()
+	if err != nil {
+		return "", err
+	}
+	defer fl.Close()
+
+	hash, err := contentHash(fl)
+	if err != nil {
+		return "", err
+	}
+
+	if f != nil {
+		f.rw.Lock()
+		f.items[name] = assetHash{size: stat.Size(), mod: stat.ModTime(), hash: hash}
+		f.rw.Unlock()
+	}
+
+	return hash, nil
+}
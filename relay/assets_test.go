@@ -0,0 +1,120 @@
+package relay
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/influx6/flux"
+)
+
+func TestAssetManifest(t *testing.T) {
+	dir, err := ioutil.TempDir("", "relay-assets")
+	if err != nil {
+		flux.FatalFailed(t, "Unable to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeTemplate(t, dir, "js/app.js", "var app;")
+	writeTemplate(t, dir, ".cache/app.js", "cached")
+
+	expect(t, FingerprintName("js/app.js", "3a7bd3e2360a00ff"), "js/app.3a7bd3e2360a.js")
+
+	name, fingerprint, ok := SplitFingerprint("js/app.3a7bd3e2360a.js")
+	expect(t, ok, true)
+	expect(t, name, "js/app.js")
+	expect(t, fingerprint, "3a7bd3e2360a")
+
+	_, _, ok = SplitFingerprint("js/jquery.min.js")
+	expect(t, ok, false)
+
+	manifest, err := BuildAssetManifest(dir)
+	if err != nil {
+		flux.FatalFailed(t, "Unable to build manifest: %s", err)
+	}
+
+	expect(t, len(manifest), 1)
+
+	assets := NewAssetPaths(dir, "static")
+	expect(t, assets.AssetPath("js/app.js"), "/static/"+manifest["js/app.js"])
+	expect(t, assets.AssetPath("missing.js"), "/static/missing.js")
+
+	file := filepath.Join(dir, DefaultManifestFile)
+	if err := (AssetManifest{"js/app.js": "js/app.built.js"}).WriteFile(file); err != nil {
+		flux.FatalFailed(t, "Unable to write manifest: %s", err)
+	}
+
+	if err := assets.LoadManifest(file); err != nil {
+		flux.FatalFailed(t, "Unable to load manifest: %s", err)
+	}
+
+	expect(t, assets.AssetPath("/js/app.js"), "/static/js/app.built.js")
+}
+
+func TestFSAssets(t *testing.T) {
+	dir, err := ioutil.TempDir("", "relay-assets")
+	if err != nil {
+		flux.FatalFailed(t, "Unable to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	var zipped bytes.Buffer
+	gw := gzip.NewWriter(&zipped)
+	gw.Write([]byte("var app;"))
+	gw.Close()
+
+	writeTemplate(t, dir, "app.js", "var app;")
+	writeTemplate(t, dir, "app.js.gz", zipped.String())
+	writeTemplate(t, dir, "app.js.br", "brotli")
+
+	router := NewChainRouter(nil, nil)
+	router.ServeDir("/static/*", dir, "static")
+
+	serve := func(path, encoding string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", "http://localhost:3000"+path, nil)
+		if encoding != "" {
+			req.Header.Set("Accept-Encoding", encoding)
+		}
+
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	//precompressed siblings are served by preference
+	rec := serve("/static/app.js", "gzip, br")
+	expect(t, rec.Header().Get("Content-Encoding"), "br")
+	expect(t, rec.Body.String(), "brotli")
+	expect(t, strings.Contains(rec.Header().Get("Content-Type"), "javascript"), true)
+
+	rec = serve("/static/app.js", "gzip, br;q=0.5")
+	expect(t, rec.Header().Get("Content-Encoding"), "gzip")
+	expect(t, rec.Body.String(), zipped.String())
+
+	rec = serve("/static/app.js", "")
+	expect(t, rec.Header().Get("Content-Encoding"), "")
+	expect(t, rec.Body.String(), "var app;")
+	expect(t, rec.Header().Get("Vary"), "Accept-Encoding")
+
+	//fingerprinted names matching the content are immutable
+	assets := NewAssetPaths(dir, "static")
+	fingerprinted := assets.AssetPath("app.js")
+
+	rec = serve(fingerprinted, "")
+	expect(t, rec.Code, http.StatusOK)
+	expect(t, rec.Body.String(), "var app;")
+	expect(t, rec.Header().Get("Cache-Control"), ImmutableCacheControl)
+
+	rec = serve(fingerprinted, "gzip")
+	expect(t, rec.Header().Get("Content-Encoding"), "gzip")
+	expect(t, rec.Header().Get("Cache-Control"), ImmutableCacheControl)
+
+	rec = serve("/static/app.000000000000.js", "")
+	expect(t, rec.Code, http.StatusNotFound)
+}
@@ -0,0 +1,148 @@
+package relay
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ErrUnsupportedMediaType is returned when a request body can not be bound due to its Content-Type
+var ErrUnsupportedMediaType = errors.New("unsupported media type for binding")
+
+// Bind decodes the request using the MessageDecoder into the struct pointed to by v
+// and validates it, see BindWith
+func (c *Context) Bind(v interface{}) error {
+	return c.BindWith(MessageDecoder, v)
+}
+
+// BindWith decodes the request using the HTTPDecoder into the struct pointed to by v,
+// choosing the format from the message type and the request Content-Type. JSON and
+// XML bodies are unmarshalled while urlencoded and multipart forms are bound through
+// the 'form' field tags. The struct is then checked with Validate, conversion and
+// validation failures are returned together as ValidationErrors
+func (c *Context) BindWith(dec HTTPDecoder, v interface{}) error {
+	msg, err := dec.Decode(c)
+	if err != nil {
+		return err
+	}
+
+	var errs ValidationErrors
+
+	if err := bindMessage(msg, c.Req.Header.Get(ContentType), v); err != nil {
+		ferrs, ok := err.(ValidationErrors)
+		if !ok {
+			return err
+		}
+		errs = append(errs, ferrs...)
+	}
+
+	if err := Validate(v); err != nil {
+		verrs, ok := err.(ValidationErrors)
+		if !ok {
+			return err
+		}
+
+		//skip fields which already failed their conversion
+		failed := make(map[string]bool)
+		for _, fe := range errs {
+			failed[fe.Field] = true
+		}
+
+		for _, fe := range verrs {
+			if !failed[fe.Field] {
+				errs = append(errs, fe)
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+// bindMessage fills v using the content of the message
+func bindMessage(msg *Message, content string, v interface{}) error {
+	switch msg.MessageType {
+	case "form", "basic":
+		return bindForm(v, msg.Form)
+	case "multipart":
+		if msg.Multipart == nil {
+			return nil
+		}
+		return bindForm(v, url.Values(msg.Multipart.Value))
+	}
+
+	if len(msg.Payload) == 0 {
+		return nil
+	}
+
+	media, _, err := mime.ParseMediaType(content)
+	if err != nil {
+		return ErrUnsupportedMediaType
+	}
+
+	switch {
+	case media == ContentJSON || strings.HasSuffix(media, "+json"):
+		return bodyError(json.Unmarshal(msg.Payload, v))
+	case media == ContentXML || media == "application/xml" || strings.HasSuffix(media, "+xml"):
+		return bodyError(xml.Unmarshal(msg.Payload, v))
+	}
+
+	return ErrUnsupportedMediaType
+}
+
+// bodyError converts the error of decoding a body, values of the wrong type become
+// ValidationErrors while malformed bodies become a 400 HTTPError
+func bodyError(err error) error {
+	switch val := err.(type) {
+	case nil:
+		return nil
+	case *json.UnmarshalTypeError:
+		return ValidationErrors{&FieldError{
+			Field:   val.Field,
+			Rule:    "type",
+			Message: fmt.Sprintf("expected %s but got %s", val.Type, val.Value),
+		}}
+	case *json.SyntaxError, *xml.SyntaxError, *strconv.NumError:
+		return NewHTTPError(http.StatusBadRequest, "malformed request body: "+err.Error()).Wrap(err)
+	}
+
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return NewHTTPError(http.StatusBadRequest, "malformed request body: unexpected end of body").Wrap(err)
+	}
+
+	return err
+}
+
+// bindForm fills the fields of v tagged with 'form' from the form values
+func bindForm(v interface{}, form url.Values) error {
+	err := bindValues(v, func(_, name string) ([]string, bool) {
+		vals, ok := form[name]
+		return vals, ok
+	}, "form")
+
+	perrs, ok := err.(ParamErrors)
+	if !ok {
+		return err
+	}
+
+	var errs ValidationErrors
+	for _, pe := range perrs {
+		errs = append(errs, &FieldError{
+			Field:   pe.Name,
+			Rule:    "type",
+			Message: pe.Err.Error(),
+		})
+	}
+
+	return errs
+}
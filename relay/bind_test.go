@@ -0,0 +1,131 @@
+package relay
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/influx6/flux"
+)
+
+type signup struct {
+	Email string   `json:"email" xml:"email" form:"email" validate:"required,email"`
+	Name  string   `json:"name" xml:"name" form:"name" validate:"required,min=2,max=10"`
+	Age   int      `json:"age" xml:"age" form:"age" validate:"min=18"`
+	Plan  string   `json:"plan" xml:"plan" form:"plan" validate:"oneof=free pro"`
+	Tags  []string `json:"tags" xml:"tags" form:"tags" validate:"max=2"`
+}
+
+func TestContextBind(t *testing.T) {
+	body := `{"email":"alex@example.com","name":"alex","age":20,"plan":"pro"}`
+	req, _ := http.NewRequest("POST", "http://localhost:3000/signup", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+
+	var user signup
+	if err := NewContext(httptest.NewRecorder(), req).Bind(&user); err != nil {
+		flux.FatalFailed(t, "Unable to bind json: %s", err)
+	}
+	expect(t, user.Name, "alex")
+	expect(t, user.Age, 20)
+
+	body = `<signup><email>alex@example.com</email><name>alex</name><age>30</age></signup>`
+	req, _ = http.NewRequest("POST", "http://localhost:3000/signup", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/xml")
+
+	user = signup{}
+	if err := NewContext(httptest.NewRecorder(), req).Bind(&user); err != nil {
+		flux.FatalFailed(t, "Unable to bind xml: %s", err)
+	}
+	expect(t, user.Age, 30)
+
+	form := "email=alex&name=a&age=old&plan=gold&tags=a&tags=b&tags=c"
+	req, _ = http.NewRequest("POST", "http://localhost:3000/signup", strings.NewReader(form))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	user = signup{}
+	err := NewContext(httptest.NewRecorder(), req).Bind(&user)
+
+	errs, ok := err.(ValidationErrors)
+	if !ok {
+		flux.FatalFailed(t, "Expected ValidationErrors but got %+v", err)
+	}
+
+	rules := make(map[string]string)
+	for _, fe := range errs {
+		rules[fe.Field] = fe.Rule
+	}
+
+	expect(t, len(errs), 5)
+	expect(t, rules["age"], "type")
+	expect(t, rules["email"], "email")
+	expect(t, rules["name"], "min")
+	expect(t, rules["plan"], "oneof")
+	expect(t, rules["tags"], "max")
+
+	req, _ = http.NewRequest("POST", "http://localhost:3000/signup", bytes.NewBufferString("name"))
+	req.Header.Set("Content-Type", "text/csv")
+
+	if err := NewContext(httptest.NewRecorder(), req).Bind(&user); err != ErrUnsupportedMediaType {
+		flux.FatalFailed(t, "Expected ErrUnsupportedMediaType but got %+v", err)
+	}
+
+	//json values of the wrong type fail like form values
+	body = `{"email":"alex@example.com","name":"alex","age":"x","plan":"pro"}`
+	req, _ = http.NewRequest("POST", "http://localhost:3000/signup", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	user = signup{}
+	err = NewContext(httptest.NewRecorder(), req).Bind(&user)
+
+	errs, ok = err.(ValidationErrors)
+	if !ok {
+		flux.FatalFailed(t, "Expected ValidationErrors but got %+v", err)
+	}
+
+	expect(t, len(errs), 1)
+	expect(t, errs[0].Field, "age")
+	expect(t, errs[0].Rule, "type")
+	expect(t, AsHTTPError(err, false).Status, http.StatusUnprocessableEntity)
+
+	//malformed bodies are bad requests
+	for content, body := range map[string]string{
+		"application/json": `{bad`,
+		"application/xml":  `<signup><age>`,
+	} {
+		req, _ = http.NewRequest("POST", "http://localhost:3000/signup", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", content)
+
+		err = NewContext(httptest.NewRecorder(), req).Bind(&signup{})
+
+		he, ok := err.(*HTTPError)
+		if !ok {
+			flux.FatalFailed(t, "Expected HTTPError for malformed %s but got %+v", content, err)
+		}
+
+		expect(t, he.Status, http.StatusBadRequest)
+	}
+}
+
+func TestValidateNegativeNumbers(t *testing.T) {
+	type balance struct {
+		Total int     `json:"total" validate:"min=0"`
+		Debt  float64 `json:"debt" validate:"max=-1"`
+	}
+
+	err := Validate(&balance{Total: -5, Debt: -2})
+
+	errs, ok := err.(ValidationErrors)
+	if !ok {
+		flux.FatalFailed(t, "Expected ValidationErrors but got %+v", err)
+	}
+
+	expect(t, len(errs), 1)
+	expect(t, errs[0].Field, "total")
+	expect(t, errs[0].Rule, "min")
+
+	if err := Validate(&balance{Total: 0, Debt: -2}); err != nil {
+		flux.FatalFailed(t, "Expected negative numbers within the rules to pass: %s", err)
+	}
+}
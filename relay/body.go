@@ -0,0 +1,97 @@
+package relay
+
+import (
+	"errors"
+	"io"
+	"net/http"
+)
+
+// body.go provides request body size limits used by the message decoders
+
+// ErrBodyTooLarge is returned when a request body exceeds the allowed body size
+var ErrBodyTooLarge = errors.New("Http Request body too large")
+
+// DefaultBodyLimit sets the maximum size in bytes of request bodies read by the
+// message decoders when no limit is set on the Context, a value <= 0 removes the limit
+var DefaultBodyLimit int64 = 32 << 20
+
+// BodyLimit returns a FlatHandler which sets the maximum body size for the requests
+// passing through it, replying with a 413 when the declared Content-Length exceeds it
+//
+//   router.Rule("post", "/upload", relay.BodyLimit(1<<20)).ChainFlat(upload)
+func BodyLimit(limit int64) FlatHandler {
+	return func(c *Context, next NextHandler) {
+		c.BodyLimit = limit
+
+		if limit > 0 && c.Req.ContentLength > limit {
+			http.Error(c.Res, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		next(c)
+	}
+}
+
+// bodyLimit returns the maximum body size for the Context
+func (c *Context) bodyLimit() int64 {
+	if c.BodyLimit != 0 {
+		return c.BodyLimit
+	}
+	return DefaultBodyLimit
+}
+
+// limitedBody provides a io.ReadCloser which fails with ErrBodyTooLarge once more
+// than the allowed bytes are read
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+	err       error
+}
+
+// newLimitedBody returns the body wrapped by a limitedBody if the limit is above zero
+func newLimitedBody(body io.ReadCloser, limit int64) io.ReadCloser {
+	if limit <= 0 {
+		return body
+	}
+
+	if lb, ok := body.(*limitedBody); ok && lb.remaining <= limit {
+		return lb
+	}
+
+	return &limitedBody{ReadCloser: body, remaining: limit}
+}
+
+// Read reads from the underline body until the limit is exceeded
+func (l *limitedBody) Read(p []byte) (int, error) {
+	if l.err != nil {
+		return 0, l.err
+	}
+
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	//read one byte more than allowed to know if the limit is exceeded
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+
+	n, err := l.ReadCloser.Read(p)
+
+	if int64(n) <= l.remaining {
+		l.remaining -= int64(n)
+		l.err = err
+		return n, err
+	}
+
+	n = int(l.remaining)
+	l.remaining = 0
+	l.err = ErrBodyTooLarge
+	return n, l.err
+}
+
+// exceeded returns true/false if the body went over its limit
+func exceeded(body io.ReadCloser) bool {
+	lb, ok := body.(*limitedBody)
+	return ok && lb.err == ErrBodyTooLarge
+}
@@ -0,0 +1,75 @@
+package relay
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/influx6/flux"
+)
+
+// chunkedRequest returns a request whose body has no declared length
+func chunkedRequest(body string) *http.Request {
+	req, _ := http.NewRequest("POST", "http://localhost:3000/foo", ioutil.NopCloser(strings.NewReader(body)))
+	req.ContentLength = -1
+	return req
+}
+
+func TestLoadDataChunked(t *testing.T) {
+	body := strings.Repeat("relay", 2000)
+
+	c := NewContext(httptest.NewRecorder(), chunkedRequest(body))
+
+	msg, err := MessageDecoder.Decode(c)
+	if err != nil {
+		flux.FatalFailed(t, "Unable to decode chunked body: %s", err)
+	}
+	expect(t, string(msg.Payload), body)
+
+	c = NewContext(httptest.NewRecorder(), chunkedRequest(body))
+	c.BodyLimit = 100
+
+	if _, err := MessageDecoder.Decode(c); err != ErrBodyTooLarge {
+		flux.FatalFailed(t, "Expected ErrBodyTooLarge but got %+v", err)
+	}
+
+	req, _ := http.NewRequest("POST", "http://localhost:3000/foo", bytes.NewBufferString(body))
+	c = NewContext(httptest.NewRecorder(), req)
+	c.BodyLimit = 100
+
+	if _, err := MessageDecoder.Decode(c); err != ErrBodyTooLarge {
+		flux.FatalFailed(t, "Expected ErrBodyTooLarge but got %+v", err)
+	}
+
+	c = NewContext(httptest.NewRecorder(), chunkedRequest(body))
+	c.BodyLimit = 100
+
+	msg, err = StreamDecoder.Decode(c)
+	if err != nil {
+		flux.FatalFailed(t, "Unable to decode stream: %s", err)
+	}
+	expect(t, msg.MessageType, "stream")
+
+	data, err := ioutil.ReadAll(msg.Body)
+	expect(t, err, ErrBodyTooLarge)
+	expect(t, len(data), 100)
+}
+
+func TestBodyLimit(t *testing.T) {
+	router := NewChainRouter(nil, nil)
+
+	var called bool
+	router.Rule("post", "/upload", BodyLimit(10)).ChainFlat(func(c *Context, next NextHandler) {
+		called = true
+	})
+
+	req, _ := http.NewRequest("POST", "http://localhost:3000/upload", bytes.NewBufferString("more than ten bytes"))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	expect(t, rec.Code, http.StatusRequestEntityTooLarge)
+	expect(t, called, false)
+}
@@ -0,0 +1,276 @@
+package relay
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+)
+
+// buffered.go provides a ResponseWriter which holds the response until the chain
+// is done, letting middleware change the status and headers after the handler
+// wrote its body
+//
+//   app.Rule("get", "/report", relay.Buffered(0)).ChainFlat(func(c *relay.Context, next relay.NextHandler) {
+//     if err := render(c.Res); err != nil {
+//       if bw, ok := c.Res.(relay.BufferedResponseWriter); ok && bw.Reset() {
+//         c.Text(500, "report unavailable")
+//       }
+//     }
+//     next(c)
+//   })
+
+// DefaultBufferThreshold provides the body size past which a buffered response is streamed
+const DefaultBufferThreshold = 1 << 20
+
+// BufferedResponseWriter provides a ResponseWriter which holds the status, headers
+// and body until committed, responses growing past the threshold are streamed
+type BufferedResponseWriter interface {
+	ResponseWriter
+	//Buffered returns true/false if the response is still held
+	Buffered() bool
+	//Reset discards the held status, body, Content-Length and ETag, it returns false once the response is streaming
+	Reset() bool
+	//Bytes returns the held body
+	Bytes() []byte
+	//Commit writes the held response with its Content-Length and ETag
+	Commit() error
+}
+
+// Buffered returns a FlatHandler which holds the responses of the rest of the
+// chain with a BufferedResponseWriter, committing them once the chain is done.
+// Bodies growing past the threshold are streamed, it defaults to DefaultBufferThreshold
+func Buffered(threshold int) FlatHandler {
+	if threshold <= 0 {
+		threshold = DefaultBufferThreshold
+	}
+
+	return func(c *Context, next NextHandler) {
+		bw := NewBufferedResponseWriter(c.Res, threshold)
+
+		res := c.Res
+		c.Res = bw
+
+		defer func() {
+			if err := bw.Commit(); err != nil {
+				c.Log.Printf("Unable to write buffered response for %s: %s", c.Req.URL.Path, err)
+			}
+			c.Res = res
+		}()
+
+		next(c)
+	}
+}
+
+// FlatBuffered returns a FlatChains which holds the responses of the chains linked to it, see Buffered
+func FlatBuffered(threshold int, lg *log.Logger) FlatChains {
+	return NewFlatChain(Buffered(threshold), lg)
+}
+
+// bufferedWriter provides the concrete implementation of BufferedResponseWriter
+type bufferedWriter struct {
+	ResponseWriter
+	buf       *bytes.Buffer
+	threshold int
+	status    int
+	size      int
+	streaming bool
+	committed bool
+}
+
+// NewBufferedResponseWriter returns a new BufferedResponseWriter over the writer,
+// taking its buffer from the BufferPool
+func NewBufferedResponseWriter(w ResponseWriter, threshold int) BufferedResponseWriter {
+	return &bufferedWriter{
+		ResponseWriter: w,
+		buf:            bufPool.Get(),
+		threshold:      threshold,
+	}
+}
+
+// WriteHeader sets the status, it can be changed until the response is streaming
+func (b *bufferedWriter) WriteHeader(code int) {
+	if b.streaming || b.committed {
+		return
+	}
+	b.status = code
+}
+
+// Write holds the data, streaming the response once it grows past the threshold
+func (b *bufferedWriter) Write(data []byte) (int, error) {
+	if b.committed && !b.streaming {
+		return 0, ErrClosed
+	}
+
+	if b.status == 0 {
+		b.status = http.StatusOK
+	}
+
+	b.size += len(data)
+
+	if b.streaming {
+		return b.ResponseWriter.Write(data)
+	}
+
+	if b.buf.Len()+len(data) > b.threshold {
+		if err := b.stream(); err != nil {
+			return 0, err
+		}
+		return b.ResponseWriter.Write(data)
+	}
+
+	return b.buf.Write(data)
+}
+
+// ReadFrom copies the reader through Write
+func (b *bufferedWriter) ReadFrom(r io.Reader) (int64, error) {
+	return io.Copy(writerOnly{b}, r)
+}
+
+// WritePayload writes the payload ignoring the type
+func (b *bufferedWriter) WritePayload(c int, p []byte) error {
+	_, err := b.Write(p)
+	return err
+}
+
+// Status returns the status of the response
+func (b *bufferedWriter) Status() int {
+	return b.status
+}
+
+// Written returns true/false if the status or body has been written
+func (b *bufferedWriter) Written() bool {
+	return b.status != 0
+}
+
+// Size returns the size of the body written
+func (b *bufferedWriter) Size() int {
+	return b.size
+}
+
+// Buffered returns true/false if the response is still held
+func (b *bufferedWriter) Buffered() bool {
+	return !b.streaming && !b.committed
+}
+
+// Bytes returns the held body
+func (b *bufferedWriter) Bytes() []byte {
+	if b.buf == nil {
+		return nil
+	}
+	return b.buf.Bytes()
+}
+
+// Reset discards the held status and body along with its Content-Length and ETag
+func (b *bufferedWriter) Reset() bool {
+	if b.streaming || b.committed {
+		return false
+	}
+
+	b.buf.Reset()
+	b.status = 0
+	b.size = 0
+
+	//the length and tag described the discarded body
+	header := b.Header()
+	header.Del(ContentLength)
+	header.Del("ETag")
+	return true
+}
+
+// Flush streams the response and flushes it
+func (b *bufferedWriter) Flush() {
+	if b.committed && !b.streaming {
+		return
+	}
+
+	if !b.streaming {
+		if b.status == 0 {
+			b.status = http.StatusOK
+		}
+		b.stream()
+	}
+
+	b.ResponseWriter.Flush()
+}
+
+// Hijack hands over the connection, the held response is discarded
+func (b *bufferedWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := b.ResponseWriter.Hijack()
+	if err == nil {
+		b.committed = true
+		b.release()
+	}
+	return conn, rw, err
+}
+
+// Commit writes the held status, headers and body with the Content-Length of
+// the body and an ETag of it if not already set
+func (b *bufferedWriter) Commit() error {
+	if b.committed {
+		return nil
+	}
+
+	b.committed = true
+
+	if b.streaming {
+		return nil
+	}
+
+	defer b.release()
+
+	if b.status == 0 {
+		return nil
+	}
+
+	header := b.Header()
+
+	if bodyAllowed(b.status) {
+		//the held body is what gets written, whatever length the handler declared
+		header.Set(ContentLength, strconv.Itoa(b.buf.Len()))
+
+		if b.buf.Len() > 0 && b.status == http.StatusOK && header.Get("ETag") == "" {
+			header.Set("ETag", BodyETag(b.buf.Bytes()))
+		}
+	}
+
+	b.ResponseWriter.WriteHeader(b.status)
+
+	_, err := b.buf.WriteTo(b.ResponseWriter)
+	return err
+}
+
+// stream writes the held head and body, sending later writes straight through
+func (b *bufferedWriter) stream() error {
+	b.streaming = true
+	defer b.release()
+
+	b.ResponseWriter.WriteHeader(b.status)
+
+	_, err := b.buf.WriteTo(b.ResponseWriter)
+	return err
+}
+
+// release returns the buffer to the BufferPool
+func (b *bufferedWriter) release() {
+	if b.buf != nil {
+		bufPool.Put(b.buf)
+		b.buf = nil
+	}
+}
+
+// bodyAllowed returns true/false if a response with the status can have a body
+func bodyAllowed(status int) bool {
+	return status >= http.StatusOK && status != http.StatusNoContent && status != http.StatusNotModified
+}
+
+// BodyETag returns a strong ETag computed from the content
+func BodyETag(content []byte) string {
+	sum := sha1.Sum(content)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
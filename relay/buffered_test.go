@@ -0,0 +1,105 @@
+package relay
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBufferedResponseWriter(t *testing.T) {
+	router := NewChainRouter(nil, nil)
+
+	//middleware can change the response after the handler wrote its body
+	router.Rule("get", "/rewrite", Buffered(64)).ChainFlat(func(c *Context, next NextHandler) {
+		next(c)
+		c.Res.WriteHeader(http.StatusAccepted)
+		c.Res.Header().Set("X-Rendered", "true")
+	}).ChainFlat(func(c *Context, next NextHandler) {
+		c.Res.Write([]byte("partial"))
+
+		bw, ok := c.Res.(BufferedResponseWriter)
+		expect(t, ok, true)
+		expect(t, string(bw.Bytes()), "partial")
+		expect(t, bw.Reset(), true)
+
+		c.Text(http.StatusOK, "rendered")
+		next(c)
+	})
+
+	router.Rule("get", "/etag", Buffered(0)).ChainFlat(ErrFlat(func(c *Context, next NextHandler) error {
+		return c.Text(http.StatusOK, "tagged")
+	}))
+
+	router.Rule("get", "/large", Buffered(8)).ChainFlat(func(c *Context, next NextHandler) {
+		c.Res.Write([]byte("12345"))
+		c.Res.Write([]byte("67890"))
+
+		bw := c.Res.(BufferedResponseWriter)
+		expect(t, bw.Buffered(), false)
+		expect(t, bw.Reset(), false)
+		expect(t, c.Res.Size(), 10)
+		next(c)
+	})
+
+	//a body rewritten after a reset does not keep the length or tag of the discarded one
+	router.Rule("get", "/reset", Buffered(0)).ChainFlat(func(c *Context, next NextHandler) {
+		c.JSON(http.StatusOK, map[string]string{"name": "relay"})
+		c.Res.Header().Set("ETag", `"stale"`)
+
+		bw := c.Res.(BufferedResponseWriter)
+		expect(t, bw.Reset(), true)
+
+		c.Res.WriteHeader(http.StatusInternalServerError)
+		c.Res.Write([]byte("err"))
+		next(c)
+	})
+
+	req, _ := http.NewRequest("GET", "http://localhost:3000/rewrite", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	expect(t, rec.Code, http.StatusAccepted)
+	expect(t, rec.Header().Get("X-Rendered"), "true")
+	expect(t, rec.Body.String(), "rendered")
+	expect(t, rec.Header().Get("Content-Length"), "8")
+
+	req, _ = http.NewRequest("GET", "http://localhost:3000/reset", nil)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	expect(t, rec.Code, http.StatusInternalServerError)
+	expect(t, rec.Body.String(), "err")
+	expect(t, rec.Header().Get("Content-Length"), "3")
+	expect(t, rec.Header().Get("ETag"), "")
+
+	req, _ = http.NewRequest("GET", "http://localhost:3000/etag", nil)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	expect(t, rec.Code, http.StatusOK)
+	expect(t, rec.Header().Get("ETag"), BodyETag([]byte("tagged")))
+
+	req, _ = http.NewRequest("GET", "http://localhost:3000/large", nil)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	expect(t, rec.Body.String(), "1234567890")
+	expect(t, rec.Header().Get("Content-Length"), "")
+	expect(t, rec.Header().Get("ETag"), "")
+
+	rec = httptest.NewRecorder()
+	bw := NewBufferedResponseWriter(NewResponseWriter(rec), 1024)
+	bw.WriteHeader(http.StatusCreated)
+	bw.WriteHeader(http.StatusAccepted)
+	bw.Write([]byte(strings.Repeat("a", 10)))
+
+	expect(t, rec.Code, http.StatusOK)
+	expect(t, rec.Body.Len(), 0)
+
+	bw.Commit()
+
+	expect(t, rec.Code, http.StatusAccepted)
+	expect(t, rec.Body.Len(), 10)
+	expect(t, rec.Header().Get("ETag"), "")
+}
@@ -0,0 +1,401 @@
+package relay
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// compress.go provides gzip and deflate compression of responses negotiated
+// through the Accept-Encoding header
+//
+//   api := app.Group("/api", relay.Compression(gzip.DefaultCompression, 1024))
+
+// DefaultCompressMinSize provides the body size below which responses are left uncompressed
+const DefaultCompressMinSize = 1024
+
+// IncompressibleTypes provides the media types, or their prefixes when ending
+// with a '/', which are already compressed and are never compressed again
+var IncompressibleTypes = []string{
+	"image/",
+	"video/",
+	"audio/",
+	"font/woff",
+	"font/woff2",
+	"application/zip",
+	"application/gzip",
+	"application/x-gzip",
+	"application/x-bzip2",
+	"application/x-7z-compressed",
+	"application/x-rar-compressed",
+	"application/pdf",
+}
+
+// compressibleImages provides image types which are text and benefit from compression
+var compressibleImages = map[string]bool{
+	"image/svg+xml": true,
+	"image/x-icon":  true,
+}
+
+// CompressPool provides a bounded pool of gzip and deflate writers for a compression level
+type CompressPool struct {
+	level   int
+	gzips   chan *gzip.Writer
+	deflate chan *flate.Writer
+}
+
+// NewCompressPool returns a new CompressPool keeping up to size writers of each
+// encoding, it panics if the level is not a valid compression level
+func NewCompressPool(size, level int) *CompressPool {
+	if _, err := gzip.NewWriterLevel(nil, level); err != nil {
+		panic(err)
+	}
+
+	return &CompressPool{
+		level:   level,
+		gzips:   make(chan *gzip.Writer, size),
+		deflate: make(chan *flate.Writer, size),
+	}
+}
+
+// Get returns a writer for the encoding writing into w, or nil for unknown encodings
+func (cp *CompressPool) Get(encoding string, w io.Writer) io.WriteCloser {
+	switch encoding {
+	case "gzip":
+		select {
+		case gw := <-cp.gzips:
+			gw.Reset(w)
+			return gw
+		default:
+			gw, _ := gzip.NewWriterLevel(w, cp.level)
+			return gw
+		}
+	case "deflate":
+		select {
+		case fw := <-cp.deflate:
+			fw.Reset(w)
+			return fw
+		default:
+			fw, _ := flate.NewWriter(w, cp.level)
+			return fw
+		}
+	}
+	return nil
+}
+
+// Put returns the writer to the pool, discarding it if the pool is full
+func (cp *CompressPool) Put(wc io.WriteCloser) {
+	switch cw := wc.(type) {
+	case *gzip.Writer:
+		cw.Reset(nil)
+		select {
+		case cp.gzips <- cw:
+		default:
+		}
+	case *flate.Writer:
+		cw.Reset(nil)
+		select {
+		case cp.deflate <- cw:
+		default:
+		}
+	}
+}
+
+// Compression returns a FlatHandler which compresses the responses of the rest of
+// the chain with gzip or deflate when accepted by the client. Bodies smaller than
+// minSize and IncompressibleTypes are written as is, minSize defaults to
+// DefaultCompressMinSize when zero. It panics if the level is invalid
+func Compression(level, minSize int) FlatHandler {
+	if minSize <= 0 {
+		minSize = DefaultCompressMinSize
+	}
+
+	pool := NewCompressPool(100, level)
+
+	return func(c *Context, next NextHandler) {
+		encoding := acceptedEncoding(c.Req)
+
+		//upgraded connections such as websockets are left alone
+		if encoding == "" || upgradeRequest(c.Req) {
+			next(c)
+			return
+		}
+
+		c.Res.Header().Add("Vary", "Accept-Encoding")
+
+		cw := &compressWriter{
+			ResponseWriter: c.Res,
+			encoding:       encoding,
+			minSize:        minSize,
+			pool:           pool,
+		}
+
+		c.Res = cw
+		defer func() {
+			cw.Close()
+			c.Res = cw.ResponseWriter
+		}()
+
+		next(c)
+	}
+}
+
+// FlatCompression returns a FlatChains which compresses the responses of the chains linked to it, see Compression
+func FlatCompression(level, minSize int, lg *log.Logger) FlatChains {
+	return NewFlatChain(Compression(level, minSize), lg)
+}
+
+// acceptedEncoding returns the encoding preferred by the request's Accept-Encoding header
+func acceptedEncoding(req *http.Request) string {
+	accept := req.Header.Get("Accept-Encoding")
+	if accept == "" {
+		return ""
+	}
+
+	ranges := parseAccept(accept)
+	gq, _ := acceptQuality(ranges, "gzip")
+	dq, _ := acceptQuality(ranges, "deflate")
+
+	switch {
+	case gq > 0 && gq >= dq:
+		return "gzip"
+	case dq > 0:
+		return "deflate"
+	}
+
+	return ""
+}
+
+// upgradeRequest returns true if the request asks for a protocol upgrade, either
+// through an Upgrade header or an 'upgrade' token within its Connection header
+func upgradeRequest(req *http.Request) bool {
+	if req.Header.Get("Upgrade") != "" {
+		return true
+	}
+
+	for _, conn := range req.Header["Connection"] {
+		for _, token := range strings.Split(conn, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), "upgrade") {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// compressWriter provides a ResponseWriter which holds the head and body until
+// it can decide on compressing the response, then writes through the compressor
+type compressWriter struct {
+	ResponseWriter
+	encoding string
+	minSize  int
+	pool     *CompressPool
+	cw       io.WriteCloser
+	buf      []byte
+	status   int
+	size     int
+	decided  bool
+	hijacked bool
+}
+
+// WriteHeader records the status to be written with the head
+func (w *compressWriter) WriteHeader(code int) {
+	if w.status != 0 || w.decided {
+		return
+	}
+	w.status = code
+}
+
+// Write writes the data into the compressor once the minimum size is reached,
+// holding it until then
+func (w *compressWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	w.size += len(b)
+
+	if !w.decided {
+		//a declared length below the minimum size is written as is right away
+		if cl, err := strconv.Atoi(w.Header().Get(ContentLength)); err == nil && cl < w.minSize && len(w.buf) == 0 {
+			w.decide(false)
+		} else {
+			w.buf = append(w.buf, b...)
+			if len(w.buf) < w.minSize {
+				return len(b), nil
+			}
+
+			w.decide(true)
+			pending := w.buf
+			w.buf = nil
+
+			if _, err := w.write(pending); err != nil {
+				return 0, err
+			}
+			return len(b), nil
+		}
+	}
+
+	return w.write(b)
+}
+
+// ReadFrom copies the reader through Write
+func (w *compressWriter) ReadFrom(r io.Reader) (int64, error) {
+	return io.Copy(writerOnly{w}, r)
+}
+
+// WritePayload writes the payload ignoring the type
+func (w *compressWriter) WritePayload(c int, p []byte) error {
+	_, err := w.Write(p)
+	return err
+}
+
+// write writes into the compressor or the response if not compressing
+func (w *compressWriter) write(b []byte) (int, error) {
+	if w.cw != nil {
+		return w.cw.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// decide writes the head, compressing the body if allowed by the response type
+func (w *compressWriter) decide(compress bool) {
+	w.decided = true
+
+	header := w.Header()
+
+	if compress {
+		compress = w.status != http.StatusNoContent && w.status != http.StatusNotModified &&
+			w.status >= http.StatusOK && header.Get("Content-Encoding") == "" &&
+			header.Get("Content-Range") == ""
+	}
+
+	if compress {
+		//sniff the type as the server would otherwise detect it from the compressed body
+		content := header.Get(ContentType)
+		if content == "" && len(w.buf) > 0 {
+			content = http.DetectContentType(w.buf)
+			header.Set(ContentType, content)
+		}
+		compress = isCompressible(content)
+	}
+
+	if compress {
+		header.Set("Content-Encoding", w.encoding)
+		header.Del(ContentLength)
+
+		//strong tags identify the uncompressed bytes, so the compressed body only keeps a weak one
+		if tag := header.Get("ETag"); tag != "" && !strings.HasPrefix(tag, "W/") {
+			header.Set("ETag", "W/"+tag)
+		}
+		w.cw = w.pool.Get(w.encoding, w.ResponseWriter)
+	}
+
+	if w.status != 0 {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+}
+
+// Flush writes the held head and body and flushes the compressor and response,
+// responses flushed before reaching the minimum size are still compressed
+func (w *compressWriter) Flush() {
+	if w.hijacked {
+		return
+	}
+
+	if !w.decided {
+		if w.status == 0 {
+			w.status = http.StatusOK
+		}
+
+		w.decide(true)
+		pending := w.buf
+		w.buf = nil
+		w.write(pending)
+	}
+
+	if fw, ok := w.cw.(interface {
+		Flush() error
+	}); ok {
+		fw.Flush()
+	}
+
+	w.ResponseWriter.Flush()
+}
+
+// Hijack hands over the connection, the compressor is no longer used
+func (w *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := w.ResponseWriter.Hijack()
+	if err == nil {
+		w.hijacked = true
+	}
+	return conn, rw, err
+}
+
+// Status returns the status of the response
+func (w *compressWriter) Status() int {
+	return w.status
+}
+
+// Written returns true/false if the status or body has been written
+func (w *compressWriter) Written() bool {
+	return w.status != 0
+}
+
+// Size returns the size of the body written before compression
+func (w *compressWriter) Size() int {
+	return w.size
+}
+
+// Close writes any held body uncompressed as it is below the minimum size, or
+// closes the compressor returning it to the pool
+func (w *compressWriter) Close() error {
+	if w.hijacked {
+		return nil
+	}
+
+	if !w.decided {
+		if w.status == 0 {
+			return nil
+		}
+
+		w.decide(false)
+		pending := w.buf
+		w.buf = nil
+		_, err := w.write(pending)
+		return err
+	}
+
+	if w.cw == nil {
+		return nil
+	}
+
+	err := w.cw.Close()
+	w.pool.Put(w.cw)
+	w.cw = nil
+	return err
+}
+
+// isCompressible returns true/false if the media type is not already compressed
+func isCompressible(content string) bool {
+	media := strings.ToLower(strings.TrimSpace(strings.Split(content, ";")[0]))
+
+	if compressibleImages[media] {
+		return true
+	}
+
+	for _, skip := range IncompressibleTypes {
+		if media == skip || (strings.HasSuffix(skip, "/") && strings.HasPrefix(media, skip)) {
+			return false
+		}
+	}
+
+	return true
+}
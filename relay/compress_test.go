@@ -0,0 +1,156 @@
+package relay
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/influx6/flux"
+)
+
+func TestCompression(t *testing.T) {
+	large := strings.Repeat("relay compression ", 200)
+
+	router := NewChainRouter(nil, nil)
+	zipped := router.Group("/", Compression(gzip.BestSpeed, 512))
+
+	zipped.Rule("get", "/large", ErrFlat(func(c *Context, next NextHandler) error {
+		return c.Text(http.StatusCreated, large)
+	}))
+
+	zipped.Rule("get", "/small", ErrFlat(func(c *Context, next NextHandler) error {
+		return c.Text(http.StatusOK, "small")
+	}))
+
+	zipped.Rule("get", "/image", ErrFlat(func(c *Context, next NextHandler) error {
+		return c.Blob(http.StatusOK, "image/png", []byte(large))
+	}))
+
+	zipped.Rule("get", "/copy", ErrFlat(func(c *Context, next NextHandler) error {
+		return c.Stream(http.StatusOK, ContentText, io.LimitReader(strings.NewReader(large), int64(len(large))))
+	}))
+
+	zipped.Rule("get", "/sniff", func(c *Context, next NextHandler) {
+		c.Res.Write(append([]byte("\x89PNG\x0D\x0A\x1A\x0A"), large...))
+		next(c)
+	})
+
+	zipped.Rule("get", "/stream", func(c *Context, next NextHandler) {
+		c.Res.Header().Set(ContentType, ContentEventStream)
+		c.Res.Write([]byte("data: 1\n\n"))
+		c.Res.Flush()
+		expect(t, c.Res.Status(), http.StatusOK)
+		expect(t, c.Res.Size(), 9)
+		next(c)
+	})
+
+	req, _ := http.NewRequest("GET", "http://localhost:3000/large", nil)
+	req.Header.Set("Accept-Encoding", "deflate;q=0.5, gzip")
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	expect(t, rec.Code, http.StatusCreated)
+	expect(t, rec.Header().Get("Content-Encoding"), "gzip")
+	expect(t, rec.Header().Get("Content-Length"), "")
+	expect(t, rec.Header().Get("Vary"), "Accept-Encoding")
+
+	gr, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		flux.FatalFailed(t, "Unable to read gzip body: %s", err)
+	}
+
+	body, _ := ioutil.ReadAll(gr)
+	expect(t, string(body), large)
+
+	//readers copied into the response go through the compressor
+	req, _ = http.NewRequest("GET", "http://localhost:3000/copy", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	expect(t, rec.Header().Get("Content-Encoding"), "gzip")
+
+	//deflate when preferred
+	req.Header.Set("Accept-Encoding", "deflate")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	expect(t, rec.Header().Get("Content-Encoding"), "deflate")
+	body, _ = ioutil.ReadAll(flate.NewReader(rec.Body))
+	expect(t, string(body), large)
+
+	//small bodies are left as is
+	req, _ = http.NewRequest("GET", "http://localhost:3000/small", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	expect(t, rec.Header().Get("Content-Encoding"), "")
+	expect(t, rec.Header().Get("Content-Length"), "5")
+	expect(t, rec.Body.String(), "small")
+
+	//compressed media types are left as is
+	req, _ = http.NewRequest("GET", "http://localhost:3000/image", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	expect(t, rec.Header().Get("Content-Encoding"), "")
+	expect(t, rec.Body.String(), large)
+
+	req, _ = http.NewRequest("GET", "http://localhost:3000/sniff", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	expect(t, rec.Header().Get("Content-Encoding"), "")
+	expect(t, rec.Header().Get("Content-Type"), "image/png")
+
+	//clients not accepting compression get the plain body
+	req, _ = http.NewRequest("GET", "http://localhost:3000/large", nil)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	expect(t, rec.Header().Get("Content-Encoding"), "")
+	expect(t, rec.Body.String(), large)
+
+	//upgrade requests are left as is
+	req.Header.Set("Accept-Encoding", "gzip")
+	req.Header.Set("Connection", "keep-alive, Upgrade")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	expect(t, rec.Header().Get("Content-Encoding"), "")
+	expect(t, rec.Body.String(), large)
+
+	req.Header.Del("Connection")
+	req.Header.Set("Upgrade", "websocket")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	expect(t, rec.Header().Get("Content-Encoding"), "")
+
+	//flushed streams are compressed right away
+	req, _ = http.NewRequest("GET", "http://localhost:3000/stream", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	expect(t, rec.Flushed, true)
+	expect(t, rec.Header().Get("Content-Encoding"), "gzip")
+
+	gr, err = gzip.NewReader(bytes.NewReader(rec.Body.Bytes()))
+	if err != nil {
+		flux.FatalFailed(t, "Unable to read gzip stream: %s", err)
+	}
+
+	body, _ = ioutil.ReadAll(gr)
+	expect(t, string(body), "data: 1\n\n")
+}
@@ -1,6 +1,9 @@
 package relay
 
-import "fmt"
+import (
+	"fmt"
+	"net/http"
+)
 
 // CustomError provides a custom error message format using a message pattern 'title: message'
 type CustomError struct {
@@ -24,3 +27,79 @@ func (c CustomError) String() string {
 func NewCustomError(title, mesg string) CustomError {
 	return CustomError{title, mesg}
 }
+
+// HTTPError provides an error carrying the details of a problem response as
+// described by RFC 7807. Extra holds any additional members of the problem
+// while Err keeps the underlying error, which is never rendered
+type HTTPError struct {
+	Status   int
+	Type     string
+	Title    string
+	Detail   string
+	Instance string
+	Extra    map[string]interface{}
+	Err      error
+}
+
+// NewHTTPError returns a new HTTPError with the status, its status text as title and the detail
+func NewHTTPError(status int, detail string) *HTTPError {
+	return &HTTPError{
+		Status: status,
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Detail: detail,
+	}
+}
+
+// Error returns the error message
+func (h *HTTPError) Error() string {
+	if h.Detail == "" {
+		return fmt.Sprintf("%d %s", h.Status, h.Title)
+	}
+	return fmt.Sprintf("%d %s: %s", h.Status, h.Title, h.Detail)
+}
+
+// With sets an additional member of the problem and returns the HTTPError
+func (h *HTTPError) With(key string, value interface{}) *HTTPError {
+	if h.Extra == nil {
+		h.Extra = make(map[string]interface{})
+	}
+	h.Extra[key] = value
+	return h
+}
+
+// Wrap sets the underlying error of the HTTPError and returns it
+func (h *HTTPError) Wrap(err error) *HTTPError {
+	h.Err = err
+	return h
+}
+
+// AsHTTPError converts the error into a HTTPError, errors returned by relay are
+// given their matching status while others become a 500. The message of unknown
+// errors is only used as the detail when debug is true
+func AsHTTPError(err error, debug bool) *HTTPError {
+	switch val := err.(type) {
+	case *HTTPError:
+		return val
+	case ValidationErrors:
+		return NewHTTPError(http.StatusUnprocessableEntity, "the request failed validation").With("errors", val).Wrap(err)
+	case ParamErrors, *ParamError:
+		return NewHTTPError(http.StatusBadRequest, err.Error()).Wrap(err)
+	}
+
+	switch err {
+	case ErrBodyTooLarge, ErrFileTooLarge, ErrTooManyFiles:
+		return NewHTTPError(http.StatusRequestEntityTooLarge, err.Error()).Wrap(err)
+	case ErrUnsupportedMediaType, ErrFileTypeNotAllowed, ErrNotMultipart:
+		return NewHTTPError(http.StatusUnsupportedMediaType, err.Error()).Wrap(err)
+	case ErrNotAcceptable:
+		return NewHTTPError(http.StatusNotAcceptable, err.Error()).Wrap(err)
+	}
+
+	he := NewHTTPError(http.StatusInternalServerError, "").Wrap(err)
+	if debug {
+		he.Detail = err.Error()
+	}
+
+	return he
+}
@@ -0,0 +1,104 @@
+package relay
+
+import (
+	"log"
+	"net/http"
+	"strings"
+)
+
+// etag.go provides conditional GET handling for dynamic responses, tagging them
+// with a strong ETag of their body and answering matching If-None-Match headers
+// with a 304
+//
+//   api := app.Group("/api", relay.ETag(0))
+
+// ETag returns a FlatHandler which holds the responses of the rest of the chain
+// for GET and HEAD requests with a BufferedResponseWriter, tagging successful
+// responses with a strong ETag of their body unless already set. Requests whose
+// If-None-Match header matches the tag get a 304 without the body. An existing
+// BufferedResponseWriter is reused, else one is created with the threshold, see Buffered
+func ETag(threshold int) FlatHandler {
+	if threshold <= 0 {
+		threshold = DefaultBufferThreshold
+	}
+
+	return func(c *Context, next NextHandler) {
+		if c.Req.Method != "GET" && c.Req.Method != "HEAD" {
+			next(c)
+			return
+		}
+
+		bw, ok := c.Res.(BufferedResponseWriter)
+		if !ok {
+			bw = NewBufferedResponseWriter(c.Res, threshold)
+
+			res := c.Res
+			c.Res = bw
+
+			defer func() {
+				if err := bw.Commit(); err != nil {
+					c.Log.Printf("Unable to write buffered response for %s: %s", c.Req.URL.Path, err)
+				}
+				c.Res = res
+			}()
+		}
+
+		next(c)
+
+		//streamed responses have already sent their head
+		if !bw.Buffered() || bw.Status() != http.StatusOK {
+			return
+		}
+
+		header := bw.Header()
+
+		tag := header.Get("ETag")
+		if tag == "" {
+			if len(bw.Bytes()) == 0 {
+				return
+			}
+
+			tag = BodyETag(bw.Bytes())
+			header.Set("ETag", tag)
+		}
+
+		if !MatchETag(c.Req.Header.Get("If-None-Match"), tag) {
+			return
+		}
+
+		bw.Reset()
+		header.Del(ContentType)
+
+		//the 304 carries the tag of the body it stands for
+		header.Set("ETag", tag)
+		bw.WriteHeader(http.StatusNotModified)
+	}
+}
+
+// FlatETag returns a FlatChains which tags the responses of the chains linked to it, see ETag
+func FlatETag(threshold int, lg *log.Logger) FlatChains {
+	return NewFlatChain(ETag(threshold), lg)
+}
+
+// MatchETag returns true/false if the tag is within the If-None-Match header value,
+// using the weak comparison where 'W/"a"' matches '"a"'
+func MatchETag(ifNoneMatch, tag string) bool {
+	ifNoneMatch = strings.TrimSpace(ifNoneMatch)
+	if ifNoneMatch == "" || tag == "" {
+		return false
+	}
+
+	if ifNoneMatch == "*" {
+		return true
+	}
+
+	tag = strings.TrimPrefix(tag, "W/")
+
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == tag {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,63 @@
+package relay
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestETag(t *testing.T) {
+	router := NewChainRouter(nil, nil)
+	tagged := router.Group("/", ETag(0))
+
+	tagged.Rule("get post", "/users", ErrFlat(func(c *Context, next NextHandler) error {
+		return c.JSON(http.StatusOK, map[string]string{"name": "alex"})
+	}))
+
+	tagged.Rule("get", "/created", ErrFlat(func(c *Context, next NextHandler) error {
+		return c.Text(http.StatusCreated, "created")
+	}))
+
+	serve := func(method, path, ifNoneMatch string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, "http://localhost:3000"+path, nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := serve("GET", "/users", "")
+	tag := rec.Header().Get("ETag")
+
+	expect(t, rec.Code, http.StatusOK)
+	expect(t, tag, BodyETag(rec.Body.Bytes()))
+
+	rec = serve("GET", "/users", `"other", `+tag)
+	expect(t, rec.Code, http.StatusNotModified)
+	expect(t, rec.Body.Len(), 0)
+	expect(t, rec.Header().Get("ETag"), tag)
+	expect(t, rec.Header().Get("Content-Type"), "")
+
+	rec = serve("GET", "/users", "W/"+tag)
+	expect(t, rec.Code, http.StatusNotModified)
+
+	rec = serve("GET", "/users", `"other"`)
+	expect(t, rec.Code, http.StatusOK)
+
+	//only successful GET and HEAD responses are tagged
+	rec = serve("POST", "/users", tag)
+	expect(t, rec.Code, http.StatusOK)
+	expect(t, rec.Header().Get("ETag"), "")
+
+	rec = serve("GET", "/created", "*")
+	expect(t, rec.Code, http.StatusCreated)
+	expect(t, rec.Header().Get("ETag"), "")
+
+	expect(t, MatchETag("*", `"a"`), true)
+	expect(t, MatchETag(`W/"a"`, `"a"`), true)
+	expect(t, MatchETag(`"b"`, `"a"`), false)
+	expect(t, MatchETag("", `"a"`), false)
+}
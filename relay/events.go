@@ -0,0 +1,343 @@
+package relay
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// events.go provides server-sent events, a one way push to clients over a plain
+// http response which is lighter than websockets and friendlier to proxies
+//
+//   news := relay.NewEventStream(100, 15*time.Second)
+//   app.Rule("get", "/news", news.Handle)
+//
+//   news.Publish(&relay.Event{Event: "headline", Data: story})
+
+// ContentEventStream header value for server-sent events
+const ContentEventStream = "text/event-stream"
+
+// LastEventID header sent by reconnecting clients with the id of the last event they received
+const LastEventID = "Last-Event-ID"
+
+// Event provides a single server-sent event, Data is sent as is when its a string
+// or []byte and as json otherwise. Multiline data is split into multiple data fields
+type Event struct {
+	ID    string
+	Event string
+	Data  interface{}
+	Retry time.Duration
+	frame []byte
+}
+
+// Encode returns the wire format of the event
+func (e *Event) Encode() ([]byte, error) {
+	var data string
+
+	switch val := e.Data.(type) {
+	case nil:
+	case string:
+		data = val
+	case []byte:
+		data = string(val)
+	default:
+		jsd, err := json.Marshal(val)
+		if err != nil {
+			return nil, err
+		}
+		data = string(jsd)
+	}
+
+	var buf bytes.Buffer
+
+	if e.ID != "" {
+		buf.WriteString("id: " + eventField(e.ID) + "\n")
+	}
+
+	if e.Event != "" {
+		buf.WriteString("event: " + eventField(e.Event) + "\n")
+	}
+
+	if e.Retry > 0 {
+		buf.WriteString("retry: " + strconv.FormatInt(int64(e.Retry/time.Millisecond), 10) + "\n")
+	}
+
+	data = strings.Replace(data, "\r\n", "\n", -1)
+	for _, line := range strings.Split(data, "\n") {
+		buf.WriteString("data: " + line + "\n")
+	}
+
+	buf.WriteString("\n")
+	return buf.Bytes(), nil
+}
+
+// eventField strips line breaks which would end the field early
+func eventField(val string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(val)
+}
+
+// EventClient provides a single client connected to an EventStream
+type EventClient struct {
+	Ctx    *Context
+	frames chan []byte
+	closer chan struct{}
+	once   sync.Once
+}
+
+// Send sends the event to only this client, the client is closed if it is too slow
+// to keep up and can recover the missed events by reconnecting
+func (ec *EventClient) Send(ev *Event) error {
+	frame, err := ev.Encode()
+	if err != nil {
+		return err
+	}
+
+	ec.send(frame)
+	return nil
+}
+
+// CloseNotify returns a channel closed once the client is closed
+func (ec *EventClient) CloseNotify() <-chan struct{} {
+	return ec.closer
+}
+
+// Close ends the client's connection
+func (ec *EventClient) Close() {
+	ec.once.Do(func() {
+		close(ec.closer)
+	})
+}
+
+// send queues the frame without blocking, closing the client if its queue is full
+func (ec *EventClient) send(frame []byte) {
+	select {
+	case <-ec.closer:
+	case ec.frames <- frame:
+	default:
+		ec.Close()
+	}
+}
+
+// EventClientHandler provides a function type that encapsulates operations on event clients
+type EventClientHandler func(*EventClient)
+
+// EventStream provides a broadcaster of server-sent events to many clients, it
+// keeps a bounded buffer of the latest events which are replayed to clients
+// reconnecting with a Last-Event-ID header
+type EventStream struct {
+	//Heartbeat sets the interval at which comments are sent to keep idle connections open, disabled when zero
+	Heartbeat time.Duration
+	//Retry sets the reconnection delay sent to clients when they connect, unset when zero
+	Retry time.Duration
+	//Queue sets the number of frames buffered for each client before it is considered too slow and closed, defaults to 64
+	Queue int
+
+	rw      sync.RWMutex
+	clients map[*EventClient]bool
+	history []*Event
+	size    int
+	lastID  int64
+	closer  chan struct{}
+	closed  bool
+}
+
+// NewEventStream returns a new EventStream keeping the given number of events for replay
+func NewEventStream(buffer int, heartbeat time.Duration) *EventStream {
+	return &EventStream{
+		Heartbeat: heartbeat,
+		Queue:     64,
+		clients:   make(map[*EventClient]bool),
+		size:      buffer,
+		closer:    make(chan struct{}),
+	}
+}
+
+// Publish sends the event to all connected clients and keeps it for replay,
+// events without an ID are given the next sequence number. The event is copied
+// so it can be reused by the caller without changing the history
+func (e *EventStream) Publish(ev *Event) error {
+	e.rw.Lock()
+	defer e.rw.Unlock()
+
+	if e.closed {
+		return ErrClosed
+	}
+
+	event := *ev
+
+	if event.ID == "" {
+		e.lastID++
+		event.ID = strconv.FormatInt(e.lastID, 10)
+	}
+
+	frame, err := event.Encode()
+	if err != nil {
+		return err
+	}
+
+	event.frame = frame
+
+	if e.size > 0 {
+		e.history = append(e.history, &event)
+		if len(e.history) > e.size {
+			e.history = e.history[len(e.history)-e.size:]
+		}
+	}
+
+	for client := range e.clients {
+		client.send(frame)
+	}
+
+	return nil
+}
+
+// Distribute calls the handler with every connected client except the one supplied
+func (e *EventStream) Distribute(fx EventClientHandler, except *EventClient) {
+	e.rw.RLock()
+	for client := range e.clients {
+		if client != except {
+			go fx(client)
+		}
+	}
+	e.rw.RUnlock()
+}
+
+// Clients returns the number of connected clients
+func (e *EventStream) Clients() int {
+	e.rw.RLock()
+	defer e.rw.RUnlock()
+	return len(e.clients)
+}
+
+// CloseNotify provides a means of checking the close state of the stream
+func (e *EventStream) CloseNotify() <-chan struct{} {
+	return e.closer
+}
+
+// Close ends the connections of all clients
+func (e *EventStream) Close() {
+	e.rw.Lock()
+	defer e.rw.Unlock()
+
+	if e.closed {
+		return
+	}
+
+	e.closed = true
+	close(e.closer)
+}
+
+// Handle provides a FlatHandler which keeps the request open as an event stream
+// until the client disconnects or the stream is closed
+func (e *EventStream) Handle(c *Context, next NextHandler) {
+	queue := e.Queue
+	if queue <= 0 {
+		queue = 64
+	}
+
+	client := &EventClient{
+		Ctx:    c,
+		frames: make(chan []byte, queue),
+		closer: make(chan struct{}),
+	}
+
+	replay, ok := e.register(client, c.Req.Header.Get(LastEventID))
+	if !ok {
+		http.Error(c.Res, ErrClosed.Error(), http.StatusServiceUnavailable)
+		next(c)
+		return
+	}
+
+	defer e.unregister(client)
+
+	headers := make(http.Header)
+	headers.Set("Cache-Control", "no-cache")
+	headers.Set("Connection", "keep-alive")
+	headers.Set("X-Accel-Buffering", "no")
+
+	BasicHeadEncoder.Encode(c, &Head{
+		Status:  http.StatusOK,
+		Content: ContentEventStream,
+		Headers: headers,
+	})
+
+	if e.Retry > 0 {
+		c.Res.Write([]byte("retry: " + strconv.FormatInt(int64(e.Retry/time.Millisecond), 10) + "\n\n"))
+	}
+
+	for _, ev := range replay {
+		c.Res.Write(ev.frame)
+	}
+
+	c.Res.Flush()
+
+	var beat <-chan time.Time
+	if e.Heartbeat > 0 {
+		ticker := time.NewTicker(e.Heartbeat)
+		defer ticker.Stop()
+		beat = ticker.C
+	}
+
+	done := c.Req.Context().Done()
+
+stream:
+	for {
+		select {
+		case <-done:
+			break stream
+		case <-e.closer:
+			break stream
+		case <-client.closer:
+			break stream
+		case frame := <-client.frames:
+			if _, err := c.Res.Write(frame); err != nil {
+				break stream
+			}
+			c.Res.Flush()
+		case <-beat:
+			if _, err := c.Res.Write([]byte(":\n\n")); err != nil {
+				break stream
+			}
+			c.Res.Flush()
+		}
+	}
+
+	next(c)
+}
+
+// register adds the client and returns the events it missed since the last event
+// id, all buffered events are replayed if the id is no longer buffered
+func (e *EventStream) register(client *EventClient, last string) ([]*Event, bool) {
+	e.rw.Lock()
+	defer e.rw.Unlock()
+
+	if e.closed {
+		return nil, false
+	}
+
+	e.clients[client] = true
+
+	if last == "" {
+		return nil, true
+	}
+
+	for ix, ev := range e.history {
+		if ev.ID == last {
+			return append([]*Event(nil), e.history[ix+1:]...), true
+		}
+	}
+
+	return append([]*Event(nil), e.history...), true
+}
+
+// unregister removes and closes the client
+func (e *EventStream) unregister(client *EventClient) {
+	e.rw.Lock()
+	delete(e.clients, client)
+	e.rw.Unlock()
+	client.Close()
+}
@@ -0,0 +1,161 @@
+package relay
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influx6/flux"
+)
+
+// readEvent reads the lines of the next frame from the event stream
+func readEvent(t *testing.T, rd *bufio.Reader) []string {
+	var lines []string
+
+	for {
+		line, err := rd.ReadString('\n')
+		if err != nil {
+			flux.FatalFailed(t, "Unable to read event: %s", err)
+		}
+
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			return lines
+		}
+
+		lines = append(lines, line)
+	}
+}
+
+func waitClients(t *testing.T, es *EventStream, count int) {
+	for i := 0; i < 100; i++ {
+		if es.Clients() == count {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	flux.FatalFailed(t, "Expected %d clients but got %d", count, es.Clients())
+}
+
+func TestEventStream(t *testing.T) {
+	es := NewEventStream(2, 0)
+	es.Retry = 3 * time.Second
+
+	router := NewChainRouter(nil, nil)
+	router.Rule("get", "/events", es.Handle)
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+	defer es.Close()
+
+	res, err := http.Get(server.URL + "/events")
+	if err != nil {
+		flux.FatalFailed(t, "Unable to connect to event stream: %s", err)
+	}
+	defer res.Body.Close()
+
+	expect(t, res.Header.Get("Content-Type"), ContentEventStream)
+	expect(t, res.Header.Get("Cache-Control"), "no-cache")
+
+	rd := bufio.NewReader(res.Body)
+	expect(t, strings.Join(readEvent(t, rd), "|"), "retry: 3000")
+
+	waitClients(t, es, 1)
+
+	es.Publish(&Event{Event: "greet", Data: "hello\nworld"})
+	expect(t, strings.Join(readEvent(t, rd), "|"), "id: 1|event: greet|data: hello|data: world")
+
+	es.Publish(&Event{Data: map[string]int{"count": 2}})
+	expect(t, strings.Join(readEvent(t, rd), "|"), `id: 2|data: {"count":2}`)
+
+	es.Publish(&Event{ID: "custom", Data: "three"})
+	expect(t, strings.Join(readEvent(t, rd), "|"), "id: custom|data: three")
+
+	//reconnecting clients get the events after their last event id
+	req, _ := http.NewRequest("GET", server.URL+"/events", nil)
+	req.Header.Set(LastEventID, "2")
+
+	replay, err := http.DefaultClient.Do(req)
+	if err != nil {
+		flux.FatalFailed(t, "Unable to reconnect to event stream: %s", err)
+	}
+	defer replay.Body.Close()
+
+	rrd := bufio.NewReader(replay.Body)
+	readEvent(t, rrd)
+	expect(t, strings.Join(readEvent(t, rrd), "|"), "id: custom|data: three")
+
+	waitClients(t, es, 2)
+
+	var sent = make(chan bool, 2)
+	es.Distribute(func(client *EventClient) {
+		client.Send(&Event{Event: "direct", Data: "only"})
+		sent <- true
+	}, nil)
+
+	<-sent
+	<-sent
+
+	expect(t, strings.Join(readEvent(t, rd), "|"), "event: direct|data: only")
+	expect(t, strings.Join(readEvent(t, rrd), "|"), "event: direct|data: only")
+
+	es.Close()
+
+	if err := es.Publish(&Event{Data: "late"}); err != ErrClosed {
+		flux.FatalFailed(t, "Expected ErrClosed: %s", err)
+	}
+
+	waitClients(t, es, 0)
+}
+
+func TestEventStreamHeartbeat(t *testing.T) {
+	es := NewEventStream(0, 10*time.Millisecond)
+	defer es.Close()
+
+	server := httptest.NewServer(FlatChainIdentity(nil).ChainFlat(es.Handle))
+	defer server.Close()
+
+	res, err := http.Get(server.URL)
+	if err != nil {
+		flux.FatalFailed(t, "Unable to connect to event stream: %s", err)
+	}
+	defer res.Body.Close()
+
+	expect(t, strings.Join(readEvent(t, bufio.NewReader(res.Body)), "|"), ":")
+}
+
+func TestEventStreamPublishCopies(t *testing.T) {
+	es := NewEventStream(4, 0)
+	defer es.Close()
+
+	router := NewChainRouter(nil, nil)
+	router.Rule("get", "/events", es.Handle)
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	//reusing an event after publishing it leaves the history untouched
+	ev := &Event{Data: "first"}
+	es.Publish(ev)
+
+	expect(t, ev.ID, "")
+
+	ev.Data = "second"
+	es.Publish(ev)
+
+	req, _ := http.NewRequest("GET", server.URL+"/events", nil)
+	req.Header.Set(LastEventID, "0")
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		flux.FatalFailed(t, "Unable to connect to event stream: %s", err)
+	}
+	defer res.Body.Close()
+
+	rd := bufio.NewReader(res.Body)
+	expect(t, strings.Join(readEvent(t, rd), "|"), "id: 1|data: first")
+	expect(t, strings.Join(readEvent(t, rd), "|"), "id: 2|data: second")
+}
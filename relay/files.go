@@ -2,38 +2,78 @@ package relay
 
 import (
 	"fmt"
-	"mime"
+	"html"
+	"io"
 	"net/http"
+	"net/url"
+	"os"
 	"path"
-	filepath "path/filepath"
+	"sort"
 	"strings"
-
-	"github.com/influx6/reggy"
+	"sync"
 )
 
-var mediaTypes = map[string]string{
-	".txt":      "text/plain",
-	".text":     "text/plain",
-	".html":     "text/html",
-	".css":      "text/css",
-	".js":       "text/javascript",
-	".erb":      "template/erb",
-	".min.css":  "text/css",
-	".haml":     "text/haml",
-	".markdown": "text/markdown",
-	".md":       "text/markdown",
-	".svg":      "image/svg+xml",
-	".png":      "image/png",
-	".jpg":      "image/jpg",
-	".gif":      "image/png",
-	".mp3":      "audio/mp3",
-}
+// DefaultIndexFile provides the file served for directory requests when FS.Index is empty
+const DefaultIndexFile = "index.html"
 
 // FS provides a configurable struct that provides a http.FileSystem with extra customization options
 type FS struct {
 	http.FileSystem
 	Strip  string
 	Header http.Header
+	//Index sets the file served for directory requests, DefaultIndexFile is used when empty
+	Index string
+	//Listing enables html listings of directories without an index file
+	Listing bool
+	//Fallback sets the file served for paths which do not exist, i.e '/index.html' for single page apps
+	Fallback string
+	//ShowHidden allows serving files and directories whose names start with a dot
+	ShowHidden bool
+	//Precompressed enables serving the '.br' and '.gz' siblings of files to clients accepting their encoding
+	Precompressed bool
+	//Fingerprints enables serving files under their fingerprinted names, i.e 'app.3a7bd3e2360a.js' for 'app.js',
+	//with an ImmutableCacheControl when the fingerprint matches the file's content hash
+	Fingerprints bool
+	//CacheControl sets the Cache-Control of files by the prefix of their url path
+	CacheControl CachePolicy
+	//Mimes sets the MimeRegistry used for the content type of files, the package's Mimes is used when nil
+	Mimes *MimeRegistry
+
+	once   sync.Once
+	hashes *fileHashes
+}
+
+// CachePolicy maps url path prefixes to the Cache-Control of the files served under them
+//
+//   relay.CachePolicy{"/static/": "public, max-age=3600", "/static/fonts/": "public, max-age=604800"}
+type CachePolicy map[string]string
+
+// Lookup returns the Cache-Control of the longest prefix matching the path, or an empty string if none match
+func (cp CachePolicy) Lookup(path string) string {
+	var prefix, cc string
+
+	for pre, val := range cp {
+		if strings.HasPrefix(path, pre) && len(pre) > len(prefix) {
+			prefix, cc = pre, val
+		}
+	}
+
+	return cc
+}
+
+// PrecompressedEncodings provides the content encodings and file extensions of
+// the precompressed siblings served by FS, in order of preference
+var PrecompressedEncodings = []struct {
+	Encoding string
+	Ext      string
+}{
+	{"br", ".br"},
+	{"gzip", ".gz"},
+}
+
+// ServeHTTP serves the file matching the request path, see FSHandler
+func (fs *FS) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	fs.serve(res, req, http.NotFound)
 }
 
 // FSCtxHandler returns a valid FlatHandler
@@ -50,164 +90,443 @@ func FSContextHandler(fs *FS, fail http.HandlerFunc) FlatHandler {
 	}
 }
 
-// FSHandler returns a valid Route.RHandler for use with the relay.Route
+// FSHandler returns a valid Route.RHandler for use with the relay.Route, serving
+// the file matching the request path once stripped of the FS.Strip prefix. Directories
+// are served through their index file or a listing if enabled and missing files
+// through the FS.Fallback file if set, else fail is called
 func FSHandler(fs *FS, fail http.HandlerFunc) RHandler {
+	if fail == nil {
+		fail = http.NotFound
+	}
+
 	return func(res http.ResponseWriter, req *http.Request, c Collector) {
-		strip := path.Clean(fmt.Sprintf("/%s/", fs.Strip))
-		requested := reggy.CleanPath(req.URL.Path)
-		file := strings.TrimPrefix(requested, strip)
+		fs.serve(res, req, fail)
+	}
+}
 
-		fi, err := fs.Open(file)
+// FSServe provides a http.Handler for serving using a http.FileSystem, the
+// options and headers of a *FS are used if supplied
+func FSServe(fs http.FileSystem, stripPrefix string, fail http.HandlerFunc) RHandler {
+	sfs, ok := fs.(*FS)
+	if ok {
+		sfs = sfs.withStrip(stripPrefix)
+	} else {
+		sfs = UseFS(fs, nil, stripPrefix)
+	}
 
-		if err != nil {
-			if fail != nil {
-				fail(res, req)
-			} else {
-				http.NotFound(res, req)
-			}
-			return
-		}
+	return FSHandler(sfs, fail)
+}
 
-		ext := strings.ToLower(filepath.Ext(file))
+// ServeDir registers a rule serving the files within the directory for GET and HEAD
+// requests matching the pattern with precompressed and fingerprinted files enabled,
+// the returned FS can be used to set its options
+//
+//   app.ServeDir("/static/*", "./static", "static").Listing = true
+func (r *ChainRouter) ServeDir(pattern, dir, strip string) *FS {
+	fs := NewFS(http.Dir(dir), strip)
+	fs.Precompressed = true
+	fs.Fingerprints = true
+	r.BareRule("get head", pattern, FSHandler(fs, nil))
+	return fs
+}
 
-		stat, err := fi.Stat()
+// withStrip returns a copy of the FS using the strip prefix, sharing its hash cache
+func (fs *FS) withStrip(strip string) *FS {
+	return &FS{
+		FileSystem:    fs.FileSystem,
+		Strip:         strip,
+		Header:        fs.Header,
+		Index:         fs.Index,
+		Listing:       fs.Listing,
+		Fallback:      fs.Fallback,
+		ShowHidden:    fs.ShowHidden,
+		Precompressed: fs.Precompressed,
+		Fingerprints:  fs.Fingerprints,
+		CacheControl:  fs.CacheControl,
+		Mimes:         fs.Mimes,
+		hashes:        fs.hashCache(),
+	}
+}
 
-		if err != nil {
-			if fail != nil {
-				fail(res, req)
-			} else {
-				http.NotFound(res, req)
-			}
-			return
+// hashCache returns the cache of the content hashes of the files served, creating
+// it on first use so FS literals cache their hashes as well
+func (fs *FS) hashCache() *fileHashes {
+	fs.once.Do(func() {
+		if fs.hashes == nil {
+			fs.hashes = newFileHashes()
 		}
+	})
+	return fs.hashes
+}
 
-		// var cext string
-		cext := mime.TypeByExtension(ext)
+// UseFS returns a custom http.FileSystem with extra extensions in tailoring response
+func UseFS(fs http.FileSystem, hd http.Header, strip string) *FS {
+	fsm := FS{
+		FileSystem: fs,
+		Strip:      strip,
+		Header:     hd,
+	}
+	return &fsm
+}
 
-		// log.Printf("Type ext: %s -> %s", ext, cext)
-		if cext == "" {
-			if types, ok := mediaTypes[ext]; ok {
-				cext = types
-			} else {
-				cext = "text/plain"
+// NewFS returns a custom http.FileSystem with extra extensions in tailoring response
+func NewFS(fs http.FileSystem, strip string) *FS {
+	fsm := FS{
+		FileSystem: fs,
+		Strip:      strip,
+		Header:     make(http.Header),
+	}
+	return &fsm
+}
+
+// ServeFile provides a file handler for serving files, it takes an indexFile which defines a default file to look for if the file path is a directory ,then the directory to use and the file to be searched for
+func ServeFile(indexFile, dir, file string, res http.ResponseWriter, req *http.Request) error {
+	fs := &FS{FileSystem: http.Dir(dir), Index: indexFile, hashes: dirHashes(dir)}
+
+	name := path.Clean("/" + file)
+
+	f, stat, err := fs.open(name)
+	if err != nil {
+		return NewCustomError("http.ServeFile.Status", fmt.Sprintf("%d", http.StatusNotFound))
+	}
+
+	if stat.IsDir() {
+		f.Close()
+
+		name = path.Join(name, fs.index())
+		f, stat, err = fs.open(name)
+		if err != nil || stat.IsDir() {
+			if err == nil {
+				f.Close()
 			}
+			return NewCustomError("http.ServeFile.Status", fmt.Sprintf("%d", http.StatusForbidden))
 		}
+	}
 
-		res.Header().Set("Content-Type", cext)
+	fs.serveContent(res, req, name, f, stat)
+	return nil
+}
 
-		if fs.Header != nil {
-			for m, v := range fs.Header {
-				for _, va := range v {
-					res.Header().Add(m, va)
-				}
-			}
+// index returns the name of the index file
+func (fs *FS) index() string {
+	if fs.Index == "" {
+		return DefaultIndexFile
+	}
+	return fs.Index
+}
+
+// filePath returns the cleaned path of the file requested by the url path, the
+// FS.Strip prefix is trimmed when the path is within it and other paths are used as is
+func (fs *FS) filePath(requested string) string {
+	requested = path.Clean("/" + requested)
+
+	strip := path.Clean("/" + fs.Strip)
+	if strip == "/" {
+		return requested
+	}
+
+	if requested == strip {
+		return "/"
+	}
+
+	if strings.HasPrefix(requested, strip+"/") {
+		return strings.TrimPrefix(requested, strip)
+	}
+
+	return requested
+}
+
+// hidden returns true/false if any part of the path starts with a dot
+func hidden(name string) bool {
+	for _, part := range strings.Split(name, "/") {
+		if strings.HasPrefix(part, ".") {
+			return true
 		}
+	}
+	return false
+}
+
+// open opens and stats the file, hidden files are reported as not existing
+func (fs *FS) open(name string) (http.File, os.FileInfo, error) {
+	if !fs.ShowHidden && hidden(name) {
+		return nil, nil, os.ErrNotExist
+	}
 
-		http.ServeContent(res, req, stat.Name(), stat.ModTime(), fi)
+	f, err := fs.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	stat, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, nil, err
 	}
+
+	return f, stat, nil
 }
 
-// FSServe provides a http.Handler for serving using a http.FileSystem
-func FSServe(fs http.FileSystem, stripPrefix string, fail http.HandlerFunc) RHandler {
-	if fail == nil {
-		fail = http.NotFound
+// serve serves the file, index file or listing for the request
+func (fs *FS) serve(res http.ResponseWriter, req *http.Request, fail http.HandlerFunc) {
+	name := fs.filePath(req.URL.Path)
+
+	f, stat, err := fs.open(name)
+	if err != nil {
+		if !fs.Fingerprints || !fs.serveFingerprinted(res, req, name) {
+			fs.fallback(res, req, fail)
+		}
+		return
 	}
 
-	return func(res http.ResponseWriter, req *http.Request, c Collector) {
-		strip := path.Clean(fmt.Sprintf("/%s/", stripPrefix))
-		requested := reggy.CleanPath(req.URL.Path)
-		file := strings.TrimPrefix(requested, strip)
+	if !stat.IsDir() {
+		fs.serveContent(res, req, name, f, stat)
+		return
+	}
 
-		fi, err := fs.Open(file)
+	//directories are redirected to their slash terminated path so relative links work
+	if !strings.HasSuffix(req.URL.Path, "/") {
+		f.Close()
 
-		if err != nil {
-			fail(res, req)
-			return
+		//the target is kept relative as an absolute path such as '//evil.com/dir/' would
+		//be taken as another host, http.Redirect is skipped as it makes targets absolute.
+		//The name is escaped so spaces or a '?' within it stay part of the path
+		target := (&url.URL{Path: path.Base(req.URL.Path) + "/"}).EscapedPath()
+		if req.URL.RawQuery != "" {
+			target += "?" + req.URL.RawQuery
 		}
 
-		ext := strings.ToLower(filepath.Ext(file))
+		res.Header().Set("Location", target)
+		res.WriteHeader(http.StatusMovedPermanently)
+		return
+	}
+
+	indexName := path.Join(name, fs.index())
+
+	index, istat, err := fs.open(indexName)
+	if err == nil && !istat.IsDir() {
+		f.Close()
+		fs.serveContent(res, req, indexName, index, istat)
+		return
+	}
+
+	if err == nil {
+		index.Close()
+	}
 
-		stat, err := fi.Stat()
+	if fs.Listing {
+		fs.list(res, f)
+		f.Close()
+		return
+	}
+
+	f.Close()
+	fail(res, req)
+}
 
+// fallback serves the FS.Fallback file for missing files if set, else calls fail
+func (fs *FS) fallback(res http.ResponseWriter, req *http.Request, fail http.HandlerFunc) {
+	if fs.Fallback == "" {
+		fail(res, req)
+		return
+	}
+
+	name := path.Clean("/" + fs.Fallback)
+
+	f, stat, err := fs.open(name)
+	if err != nil || stat.IsDir() {
+		if err == nil {
+			f.Close()
+		}
+		fail(res, req)
+		return
+	}
+
+	fs.serveContent(res, req, name, f, stat)
+}
+
+// serveFingerprinted serves the file named by the fingerprinted name with an
+// ImmutableCacheControl, it returns false if the name carries no fingerprint or
+// the fingerprint does not match the file's content hash
+func (fs *FS) serveFingerprinted(res http.ResponseWriter, req *http.Request, name string) bool {
+	original, fingerprint, ok := SplitFingerprint(name)
+	if !ok {
+		return false
+	}
+
+	f, stat, err := fs.open(original)
+	if err != nil {
+		return false
+	}
+
+	if stat.IsDir() {
+		f.Close()
+		return false
+	}
+
+	hash, err := fs.hashCache().hash(original, stat, func() (io.ReadCloser, error) {
+		return fs.Open(original)
+	})
+
+	if err != nil || !strings.HasPrefix(hash, fingerprint) {
+		f.Close()
+		return false
+	}
+
+	res.Header().Set("Cache-Control", ImmutableCacheControl)
+	fs.serveContent(res, req, original, f, stat)
+	return true
+}
+
+// precompressed returns the encoding and name of the sibling of the file preferred
+// by the request's Accept-Encoding header, it returns false if none is accepted or exists
+func (fs *FS) precompressed(req *http.Request, name string) (string, string, http.File, os.FileInfo, bool) {
+	accept := req.Header.Get("Accept-Encoding")
+	if accept == "" {
+		return "", "", nil, nil, false
+	}
+
+	ranges := parseAccept(accept)
+
+	var best float64
+	var encoding, sibling string
+	var file http.File
+	var stat os.FileInfo
+
+	for _, pc := range PrecompressedEncodings {
+		q, _ := acceptQuality(ranges, pc.Encoding)
+		if q <= best {
+			continue
+		}
+
+		f, fstat, err := fs.open(name + pc.Ext)
 		if err != nil {
-			fail(res, req)
-			return
+			continue
 		}
 
-		// var cext string
-		cext := mime.TypeByExtension(ext)
+		if fstat.IsDir() {
+			f.Close()
+			continue
+		}
 
-		// log.Printf("Type ext: %s -> %s", ext, cext)
-		if cext == "" {
-			if types, ok := mediaTypes[ext]; ok {
-				cext = types
-			} else {
-				cext = "text/plain"
-			}
+		if file != nil {
+			file.Close()
 		}
 
-		res.Header().Set("Content-Type", cext)
-		http.ServeContent(res, req, stat.Name(), stat.ModTime(), fi)
+		best, encoding, sibling, file, stat = q, pc.Encoding, name+pc.Ext, f, fstat
 	}
+
+	return encoding, sibling, file, stat, file != nil
 }
 
-// UseFS returns a custom http.FileSystem with extra extensions in tailoring response
-func UseFS(fs http.FileSystem, hd http.Header, strip string) *FS {
-	fsm := FS{
-		FileSystem: fs,
-		Strip:      strip,
-		Header:     hd,
+// contentType returns the media type of the file from its name, sniffing the
+// start of its content when the name is unknown to the MimeRegistry
+func (fs *FS) contentType(name string, f http.File) string {
+	mimes := fs.Mimes
+	if mimes == nil {
+		mimes = Mimes
 	}
-	return &fsm
-}
 
-// NewFS returns a custom http.FileSystem with extra extensions in tailoring response
-func NewFS(fs http.FileSystem, strip string) *FS {
-	fsm := FS{
-		FileSystem: fs,
-		Strip:      strip,
-		Header:     make(http.Header),
+	if media, ok := mimes.Lookup(name); ok {
+		return media
 	}
-	return &fsm
+
+	var sniff [512]byte
+	n, _ := io.ReadFull(f, sniff[:])
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "application/octet-stream"
+	}
+
+	return mimes.TypeOf(name, sniff[:n])
 }
 
-// ServeFile provides a file handler for serving files, it takes an indexFile which defines a default file to look for if the file path is a directory ,then the directory to use and the file to be searched for
-func ServeFile(indexFile, dir, file string, res http.ResponseWriter, req *http.Request) error {
-	fs := http.Dir(dir)
-	f, err := fs.Open(file)
+// serveContent writes the file with the content type of its name, the FS.Header,
+// its Cache-Control and a strong ETag of its content, or its precompressed sibling
+// if enabled, closing it once done. Conditional requests are answered by http.ServeContent
+func (fs *FS) serveContent(res http.ResponseWriter, req *http.Request, name string, f http.File, stat os.FileInfo) {
+	header := res.Header()
+	header.Set("Content-Type", fs.contentType(name, f))
 
-	// log.Printf("opening file from %s for %s -> %s", dir, file, err)
+	if fs.Precompressed {
+		header.Add("Vary", "Accept-Encoding")
 
-	if err != nil {
-		return NewCustomError("http.ServeFile.Status", fmt.Sprintf("%d", http.StatusNotFound))
+		if encoding, sibling, cf, cstat, ok := fs.precompressed(req, name); ok {
+			f.Close()
+			f, stat, name = cf, cstat, sibling
+			header.Set("Content-Encoding", encoding)
+		}
 	}
 
-	ext := strings.ToLower(filepath.Ext(file))
+	defer f.Close()
 
-	fi, _ := f.Stat()
-	if fi.IsDir() {
-		file = filepath.Join(file, indexFile)
-		f, err = fs.Open(file)
-		if err != nil {
-			return NewCustomError("http.ServeFile.Status", fmt.Sprintf("%d", http.StatusForbidden))
+	for m, v := range fs.Header {
+		for _, va := range v {
+			header.Add(m, va)
 		}
-		fi, _ = f.Stat()
 	}
 
-	// var cext string
-	cext := mime.TypeByExtension(ext)
+	if header.Get("X-Content-Type-Options") == "" {
+		header.Set("X-Content-Type-Options", "nosniff")
+	}
 
-	// log.Printf("Type ext: %s -> %s", ext, cext)
-	if cext == "" {
-		if types, ok := mediaTypes[ext]; ok {
-			cext = types
-		} else {
-			cext = "text/plain"
+	if header.Get("Cache-Control") == "" {
+		if cc := fs.CacheControl.Lookup(req.URL.Path); cc != "" {
+			header.Set("Cache-Control", cc)
 		}
 	}
 
-	res.Header().Set("Content-Type", cext)
+	if header.Get("ETag") == "" {
+		hash, err := fs.hashCache().hash(name, stat, func() (io.ReadCloser, error) {
+			return fs.Open(name)
+		})
 
-	http.ServeContent(res, req, fi.Name(), fi.ModTime(), f)
-	return nil
+		if err == nil {
+			header.Set("ETag", `"`+hash[:32]+`"`)
+		}
+	}
+
+	http.ServeContent(res, req, stat.Name(), stat.ModTime(), f)
+}
+
+// list writes a html listing of the directory, hiding dotfiles unless allowed
+func (fs *FS) list(res http.ResponseWriter, dir http.File) {
+	items, err := dir.Readdir(-1)
+	if err != nil {
+		http.Error(res, "Unable to read directory", http.StatusInternalServerError)
+		return
+	}
+
+	sort.Sort(byFileName(items))
+
+	for m, v := range fs.Header {
+		for _, va := range v {
+			res.Header().Add(m, va)
+		}
+	}
+
+	res.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+	fmt.Fprintf(res, "<pre>\n")
+	for _, item := range items {
+		name := item.Name()
+
+		if !fs.ShowHidden && strings.HasPrefix(name, ".") {
+			continue
+		}
+
+		if item.IsDir() {
+			name += "/"
+		}
+
+		link := url.URL{Path: name}
+		fmt.Fprintf(res, "<a href=\"%s\">%s</a>\n", link.String(), html.EscapeString(name))
+	}
+	fmt.Fprintf(res, "</pre>\n")
 }
+
+// byFileName provides sorting of file infos by name
+type byFileName []os.FileInfo
+
+func (b byFileName) Len() int           { return len(b) }
+func (b byFileName) Less(i, j int) bool { return b[i].Name() < b[j].Name() }
+func (b byFileName) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
@@ -0,0 +1,254 @@
+package relay
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influx6/flux"
+)
+
+func TestFSHandler(t *testing.T) {
+	dir, err := ioutil.TempDir("", "relay-files")
+	if err != nil {
+		flux.FatalFailed(t, "Unable to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeTemplate(t, dir, "secret.txt", "secret")
+	writeTemplate(t, dir, "public/index.html", "<h1>home</h1>")
+	writeTemplate(t, dir, "public/app.js", "var app;")
+	writeTemplate(t, dir, "public/.env", "TOKEN=1")
+	writeTemplate(t, dir, "public/.git/config", "[core]")
+	writeTemplate(t, dir, "public/docs/readme.txt", "readme")
+	writeTemplate(t, dir, "public/docs/guide.md", "guide")
+	writeTemplate(t, dir, "public/my docs/notes.txt", "notes")
+	writeTemplate(t, dir, "public/what?/faq.txt", "faq")
+
+	fs := NewFS(http.Dir(filepath.Join(dir, "public")), "static")
+	fs.Header.Set("X-Static", "true")
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", "http://localhost:3000"+path, nil)
+		rec := httptest.NewRecorder()
+		fs.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := serve("/static/app.js")
+	expect(t, rec.Code, http.StatusOK)
+	expect(t, rec.Body.String(), "var app;")
+	expect(t, rec.Header().Get("X-Static"), "true")
+
+	//directories are served through their index file
+	rec = serve("/static/")
+	expect(t, rec.Code, http.StatusOK)
+	expect(t, rec.Body.String(), "<h1>home</h1>")
+	expect(t, strings.HasPrefix(rec.Header().Get("Content-Type"), "text/html"), true)
+
+	rec = serve("/static/docs")
+	expect(t, rec.Code, http.StatusMovedPermanently)
+	expect(t, rec.Header().Get("Location"), "docs/")
+
+	rec = serve("/static?page=2")
+	expect(t, rec.Header().Get("Location"), "static/?page=2")
+
+	//redirects stay relative so paths can not name another host
+	fs.Strip = ""
+	rec = serve("//evil.com/../docs")
+	expect(t, rec.Code, http.StatusMovedPermanently)
+	expect(t, rec.Header().Get("Location"), "docs/")
+	fs.Strip = "static"
+
+	//redirect targets are escaped
+	rec = serve("/static/my%20docs?page=2")
+	expect(t, rec.Code, http.StatusMovedPermanently)
+	expect(t, rec.Header().Get("Location"), "my%20docs/?page=2")
+
+	rec = serve("/static/what%3F")
+	expect(t, rec.Code, http.StatusMovedPermanently)
+	expect(t, rec.Header().Get("Location"), "what%3F/")
+
+	rec = serve("/static/docs/")
+	expect(t, rec.Code, http.StatusNotFound)
+
+	fs.Listing = true
+	rec = serve("/static/docs/")
+	expect(t, rec.Code, http.StatusOK)
+	expect(t, rec.Body.String(), "<pre>\n<a href=\"guide.md\">guide.md</a>\n<a href=\"readme.txt\">readme.txt</a>\n</pre>\n")
+
+	rec = serve("/static/")
+	expect(t, rec.Body.String(), "<h1>home</h1>")
+
+	//dotfiles are hidden unless allowed
+	expect(t, serve("/static/.env").Code, http.StatusNotFound)
+	expect(t, serve("/static/.git/config").Code, http.StatusNotFound)
+
+	fs.ShowHidden = true
+	expect(t, serve("/static/.env").Code, http.StatusOK)
+	fs.ShowHidden = false
+
+	//paths can not escape the directory
+	expect(t, serve("/static/../secret.txt").Code, http.StatusNotFound)
+	expect(t, serve("/static/%2e%2e/secret.txt").Code, http.StatusNotFound)
+	expect(t, serve("/static/..%2fsecret.txt").Code, http.StatusNotFound)
+
+	//the prefix is only trimmed on a whole segment, other paths are served as is
+	expect(t, serve("/staticapp.js").Code, http.StatusNotFound)
+
+	rec = serve("/app.js")
+	expect(t, rec.Code, http.StatusOK)
+	expect(t, rec.Body.String(), "var app;")
+
+	//missing files are served with the fallback for single page apps
+	fs.Fallback = "index.html"
+	rec = serve("/static/users/10")
+	expect(t, rec.Code, http.StatusOK)
+	expect(t, rec.Body.String(), "<h1>home</h1>")
+
+	//FSServe keeps the headers of the FS
+	req, _ := http.NewRequest("GET", "http://localhost:3000/assets/app.js", nil)
+	rec = httptest.NewRecorder()
+	FSServe(fs, "assets", nil)(rec, req, nil)
+
+	expect(t, rec.Body.String(), "var app;")
+	expect(t, rec.Header().Get("X-Static"), "true")
+
+	//ServeFile uses the type of the index file and reports missing files
+	req, _ = http.NewRequest("GET", "http://localhost:3000/", nil)
+	rec = httptest.NewRecorder()
+
+	if err := ServeFile("index.html", filepath.Join(dir, "public"), "/", rec, req); err != nil {
+		flux.FatalFailed(t, "Unable to serve index file: %s", err)
+	}
+
+	expect(t, strings.HasPrefix(rec.Header().Get("Content-Type"), "text/html"), true)
+
+	if err := ServeFile("index.html", filepath.Join(dir, "public"), "/missing.js", httptest.NewRecorder(), req); err == nil {
+		flux.FatalFailed(t, "Expected missing file to fail")
+	}
+
+	if err := ServeFile("index.html", filepath.Join(dir, "public"), "/docs", httptest.NewRecorder(), req); err == nil {
+		flux.FatalFailed(t, "Expected directory without index file to fail")
+	}
+}
+
+func TestServeDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "relay-files")
+	if err != nil {
+		flux.FatalFailed(t, "Unable to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeTemplate(t, dir, "app.css", "body{}")
+
+	router := NewChainRouter(nil, nil)
+	router.ServeDir("/static/*", dir, "static")
+
+	req, _ := http.NewRequest("GET", "http://localhost:3000/static/app.css", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	expect(t, rec.Code, http.StatusOK)
+	expect(t, rec.Body.String(), "body{}")
+	expect(t, strings.HasPrefix(rec.Header().Get("Content-Type"), "text/css"), true)
+}
+
+func TestFSCaching(t *testing.T) {
+	dir, err := ioutil.TempDir("", "relay-files")
+	if err != nil {
+		flux.FatalFailed(t, "Unable to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeTemplate(t, dir, "app.css", "body{}")
+	writeTemplate(t, dir, "fonts/icons.woff", "woff")
+
+	fs := NewFS(http.Dir(dir), "static")
+	fs.CacheControl = CachePolicy{
+		"/static/":       "public, max-age=3600",
+		"/static/fonts/": "public, max-age=604800",
+	}
+
+	serve := func(path, ifNoneMatch string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", "http://localhost:3000"+path, nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+
+		rec := httptest.NewRecorder()
+		fs.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := serve("/static/app.css", "")
+	tag := rec.Header().Get("ETag")
+
+	expect(t, rec.Code, http.StatusOK)
+	expect(t, len(tag), 34)
+	expect(t, rec.Header().Get("Cache-Control"), "public, max-age=3600")
+
+	rec = serve("/static/app.css", tag)
+	expect(t, rec.Code, http.StatusNotModified)
+
+	rec = serve("/static/fonts/icons.woff", "")
+	expect(t, rec.Header().Get("Cache-Control"), "public, max-age=604800")
+
+	//tags change with the content
+	future := time.Now().Add(time.Minute)
+	writeTemplate(t, dir, "app.css", "body{color:red}")
+	os.Chtimes(filepath.Join(dir, "app.css"), future, future)
+
+	rec = serve("/static/app.css", tag)
+	expect(t, rec.Code, http.StatusOK)
+
+	if rec.Header().Get("ETag") == tag {
+		flux.FatalFailed(t, "Expected ETag to change with the content")
+	}
+}
+
+func TestFSHashCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "relay-files")
+	if err != nil {
+		flux.FatalFailed(t, "Unable to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeTemplate(t, dir, "app.css", "body{}")
+
+	//FS literals create their cache on first use
+	fs := &FS{FileSystem: http.Dir(dir)}
+
+	req, _ := http.NewRequest("GET", "http://localhost:3000/app.css", nil)
+	rec := httptest.NewRecorder()
+	fs.ServeHTTP(rec, req)
+
+	expect(t, rec.Code, http.StatusOK)
+	expect(t, len(fs.hashCache().items), 1)
+
+	//copies made by FSServe share the cache
+	copied := FSServe(fs, "static", nil)
+
+	req, _ = http.NewRequest("GET", "http://localhost:3000/static/app.css", nil)
+	rec = httptest.NewRecorder()
+	copied(rec, req, nil)
+
+	expect(t, rec.Code, http.StatusOK)
+	expect(t, len(fs.hashCache().items), 1)
+
+	//ServeFile keeps a cache for each directory
+	req, _ = http.NewRequest("GET", "http://localhost:3000/app.css", nil)
+	rec = httptest.NewRecorder()
+
+	if err := ServeFile("", dir, "app.css", rec, req); err != nil {
+		flux.FatalFailed(t, "Unable to serve file: %s", err)
+	}
+
+	expect(t, rec.Code, http.StatusOK)
+	expect(t, len(dirHashes(dir).items), 1)
+}
@@ -0,0 +1,217 @@
+package relay
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"time"
+)
+
+// funcs.go provides the default functions available to html templates
+//
+//   {{.Created | date "Jan 2, 2006"}}
+//   {{.Count}} {{pluralize .Count "comment"}}
+//   {{template "partials/user" dict "User" .User "Admin" true}}
+//   <script>var user = {{json .User}};</script>
+//   <form>{{csrfField .CSRF}}</form>
+
+// CSRFFieldName provides the name of the form field written by the 'csrfField' template function
+const CSRFFieldName = "csrf_token"
+
+// ErrOddDictArgs is returned by the 'dict' template function when given a key without a value
+var ErrOddDictArgs = errors.New("dict requires key and value pairs")
+
+// DefaultFuncs returns a new FuncMap with the default template functions, it
+// is used by the Templates registry and can be added to any html template
+func DefaultFuncs() template.FuncMap {
+	return template.FuncMap{
+		"json":      SafeJSON,
+		"date":      FormatDate,
+		"pluralize": Pluralize,
+		"dict":      Dict,
+		"list":      List,
+		"csrfField": CSRFField,
+	}
+}
+
+// SafeJSON returns the value as json safe for embedding within script tags and attributes
+func SafeJSON(v interface{}) (template.JS, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return template.JS(data), nil
+}
+
+// FormatDate formats a time.Time or *time.Time with the layout, zero times format as an empty string
+func FormatDate(layout string, v interface{}) (string, error) {
+	var tm time.Time
+
+	switch val := v.(type) {
+	case time.Time:
+		tm = val
+	case *time.Time:
+		if val == nil {
+			return "", nil
+		}
+		tm = *val
+	default:
+		return "", fmt.Errorf("date expects a time.Time not %T", v)
+	}
+
+	if tm.IsZero() {
+		return "", nil
+	}
+
+	return tm.Format(layout), nil
+}
+
+// Pluralize returns the singular form when the count is one and the plural form
+// otherwise, the plural defaults to the singular with an 's' suffix
+func Pluralize(count interface{}, singular string, plural ...string) (string, error) {
+	var n float64
+
+	rv := reflect.ValueOf(count)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n = float64(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n = float64(rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		n = rv.Float()
+	case reflect.Slice, reflect.Map, reflect.Array:
+		n = float64(rv.Len())
+	default:
+		return "", fmt.Errorf("pluralize expects a number or collection not %T", count)
+	}
+
+	if n == 1 {
+		return singular, nil
+	}
+
+	if len(plural) > 0 {
+		return plural[0], nil
+	}
+
+	return singular + "s", nil
+}
+
+// Dict returns a map built from the key and value pairs, useful for passing
+// multiple values to a template
+func Dict(pairs ...interface{}) (map[string]interface{}, error) {
+	if len(pairs)%2 != 0 {
+		return nil, ErrOddDictArgs
+	}
+
+	dict := make(map[string]interface{}, len(pairs)/2)
+
+	for i := 0; i < len(pairs); i += 2 {
+		key, ok := pairs[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("dict keys must be strings not %T", pairs[i])
+		}
+		dict[key] = pairs[i+1]
+	}
+
+	return dict, nil
+}
+
+// List returns the values as a slice, it is named to leave the builtin 'slice' in place
+func List(items ...interface{}) []interface{} {
+	return items
+}
+
+// CSRFField returns a hidden form input carrying the csrf token
+func CSRFField(token string) template.HTML {
+	return template.HTML(fmt.Sprintf(`<input type="hidden" name="%s" value="%s">`, CSRFFieldName, template.HTMLEscapeString(token)))
+}
+
+// AssetPaths provides url paths for static files carrying a hash of their content,
+// allowing them to be cached by clients until they change. Hashes are cached and
+// only recomputed when the file's size or modtime changes
+type AssetPaths struct {
+	Dir    string
+	Prefix string
+	//Manifest maps asset names to their fingerprinted names, names missing from it are fingerprinted from their content
+	Manifest AssetManifest
+
+	hashes *fileHashes
+}
+
+// NewAssetPaths returns a new AssetPaths for the files in the directory served under the prefix
+func NewAssetPaths(dir, prefix string) *AssetPaths {
+	return &AssetPaths{
+		Dir:    dir,
+		Prefix: prefix,
+		hashes: newFileHashes(),
+	}
+}
+
+// Path returns the url path of the asset with a version of its content hash,
+// i.e '/static/css/app.css?v=3a7bd3e2360a'. Files which can not be read get
+// their path without a version
+func (a *AssetPaths) Path(name string) string {
+	url := path.Join("/", a.Prefix, name)
+
+	hash, err := a.hash(name)
+	if err != nil {
+		return url
+	}
+
+	return url + "?v=" + hash[:FingerprintSize]
+}
+
+// AssetPath returns the url path of the fingerprinted asset from the Manifest or
+// its content hash, i.e '/static/css/app.3a7bd3e2360a.css'. Files which can not
+// be read get their path as is
+func (a *AssetPaths) AssetPath(name string) string {
+	if fingerprinted, ok := a.Manifest[path.Clean("/" + name)[1:]]; ok {
+		return path.Join("/", a.Prefix, fingerprinted)
+	}
+
+	hash, err := a.hash(name)
+	if err != nil {
+		return path.Join("/", a.Prefix, name)
+	}
+
+	return path.Join("/", a.Prefix, FingerprintName(name, hash))
+}
+
+// LoadManifest sets the Manifest from the json file
+func (a *AssetPaths) LoadManifest(file string) error {
+	manifest, err := LoadAssetManifest(file)
+	if err != nil {
+		return err
+	}
+
+	a.Manifest = manifest
+	return nil
+}
+
+// FuncMap returns a FuncMap with the 'asset' and 'assetPath' template functions
+func (a *AssetPaths) FuncMap() template.FuncMap {
+	return template.FuncMap{
+		"asset":     a.Path,
+		"assetPath": a.AssetPath,
+	}
+}
+
+// hash returns the content hash of the file, using the cached hash when the file is unchanged
+func (a *AssetPaths) hash(name string) (string, error) {
+	file := filepath.Join(a.Dir, filepath.FromSlash(path.Clean("/"+name)))
+
+	stat, err := os.Stat(file)
+	if err != nil {
+		return "", err
+	}
+
+	return a.hashes.hash(name, stat, func() (io.ReadCloser, error) {
+		return os.Open(file)
+	})
+}
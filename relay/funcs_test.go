@@ -0,0 +1,90 @@
+package relay
+
+import (
+	"bytes"
+	"html/template"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influx6/flux"
+)
+
+func TestDefaultFuncs(t *testing.T) {
+	router := NewChainRouter(nil, nil)
+	router.NamedRule("user", "get", `/users/{id:[\d]+}`, func(c *Context, next NextHandler) {
+		next(c)
+	})
+
+	dir, err := ioutil.TempDir("", "relay-assets")
+	if err != nil {
+		flux.FatalFailed(t, "Unable to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "app.css"), []byte("body{}"), 0644); err != nil {
+		flux.FatalFailed(t, "Unable to write asset: %s", err)
+	}
+
+	assets := NewAssetPaths(dir, "static")
+
+	src := `{{url "user" "id" 20}}|{{asset "app.css"}}|{{asset "missing.js"}}|{{json .User}}|{{.Created | date "2006-01-02"}}|` +
+		`{{pluralize 1 "item"}} {{pluralize .Count "item"}} {{pluralize 2 "child" "children"}}|` +
+		`{{with dict "Name" "alex" "Age" 20}}{{.Name}}:{{.Age}}{{end}}|{{range list 1 2 3}}{{.}}{{end}}|{{csrfField .Token}}|{{slice .Word 1 3}}`
+
+	tl := template.Must(template.New("page").Funcs(DefaultFuncs()).Funcs(router.FuncMap()).Funcs(assets.FuncMap()).Parse(src))
+
+	var buf bytes.Buffer
+	err = tl.Execute(&buf, map[string]interface{}{
+		"User":    map[string]string{"name": "</script>"},
+		"Created": time.Date(2016, 3, 4, 0, 0, 0, 0, time.UTC),
+		"Count":   []int{1, 2},
+		"Token":   `a"b`,
+		"Word":    "relay",
+	})
+
+	if err != nil {
+		flux.FatalFailed(t, "Unable to execute template: %s", err)
+	}
+
+	parts := strings.Split(buf.String(), "|")
+
+	expect(t, parts[0], "/users/20")
+
+	if !strings.HasPrefix(parts[1], "/static/app.css?v=") || len(parts[1]) != len("/static/app.css?v=")+12 {
+		flux.FatalFailed(t, "Expected hashed asset path: %s", parts[1])
+	}
+
+	expect(t, parts[2], "/static/missing.js")
+
+	if strings.Contains(parts[3], "</script>") {
+		flux.FatalFailed(t, "Expected json to be escaped: %s", parts[3])
+	}
+
+	expect(t, parts[4], "2016-03-04")
+	expect(t, parts[5], "item items children")
+	expect(t, parts[6], "alex:20")
+	expect(t, parts[7], "123")
+	expect(t, parts[8], `<input type="hidden" name="csrf_token" value="a&#34;b">`)
+
+	//the builtin slice is left in place
+	expect(t, parts[9], "el")
+
+	if _, err := Dict("key"); err != ErrOddDictArgs {
+		flux.FatalFailed(t, "Expected ErrOddDictArgs: %s", err)
+	}
+
+	//hashes change with the content
+	first := assets.Path("app.css")
+
+	future := time.Now().Add(time.Minute)
+	ioutil.WriteFile(filepath.Join(dir, "app.css"), []byte("body{color:red}"), 0644)
+	os.Chtimes(filepath.Join(dir, "app.css"), future, future)
+
+	if assets.Path("app.css") == first {
+		flux.FatalFailed(t, "Expected asset hash to change with its content")
+	}
+}
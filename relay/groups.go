@@ -0,0 +1,99 @@
+package relay
+
+import "strings"
+
+// RouteGroup provides a set of rules registered on a ChainRouter sharing a path prefix and a middleware stack which only applies to the rules within the group
+type RouteGroup struct {
+	router     *ChainRouter
+	prefix     string
+	host       *hostPattern
+	middleware []FlatHandler
+}
+
+// Group returns a new RouteGroup whose rules are prefixed with the given path and run through the supplied middlewares before their handlers
+//
+//   api := router.Group("/api/v1", auth, logger)
+//   api.Rule("get", "/users/:id", users.Get) // => /api/v1/users/:id
+func (r *ChainRouter) Group(prefix string, mw ...FlatHandler) *RouteGroup {
+	return &RouteGroup{
+		router:     r,
+		prefix:     cleanRoutePath(prefix),
+		middleware: mw,
+	}
+}
+
+// Group returns a new RouteGroup nested within this group, inheriting its prefix and middlewares
+func (g *RouteGroup) Group(prefix string, mw ...FlatHandler) *RouteGroup {
+	stack := make([]FlatHandler, 0, len(g.middleware)+len(mw))
+	stack = append(stack, g.middleware...)
+	stack = append(stack, mw...)
+
+	return &RouteGroup{
+		router:     g.router,
+		prefix:     joinRoutePath(g.prefix, prefix),
+		host:       g.host,
+		middleware: stack,
+	}
+}
+
+// Prefix returns the path prefix of the group
+func (g *RouteGroup) Prefix() string {
+	return g.prefix
+}
+
+// Rule defines a matching rule within the group which returns a flatchain
+func (g *RouteGroup) Rule(mo, pattern string, fx FlatHandler) FlatChains {
+	return g.NamedRule("", mo, pattern, fx)
+}
+
+// NamedRule defines a matching rule within the group which returns a flatchain and can be referred to by its name when building urls
+func (g *RouteGroup) NamedRule(name, mo, pattern string, fx FlatHandler) FlatChains {
+	if fx == nil {
+		fx = IdentityCall
+	}
+
+	fr := NewFlatChain(fx, g.router.Log)
+	g.router.chainRule(name, mo, joinRoutePath(g.prefix, pattern), g.host, fx, g.stack(fr))
+	return fr
+}
+
+// BareRule defines a matching rule within the group for a specified pattern
+func (g *RouteGroup) BareRule(mo, pattern string, fx RHandler) {
+	g.NamedBareRule("", mo, pattern, fx)
+}
+
+// NamedBareRule defines a matching rule within the group for a specified pattern which can be referred to by its name when building urls
+func (g *RouteGroup) NamedBareRule(name, mo, pattern string, fx RHandler) {
+	fr := NewFlatChain(func(c *Context, next NextHandler) {
+		fx(c.Res, c.Req, c.ToMap())
+		next(c)
+	}, g.router.Log)
+
+	g.router.chainRule(name, mo, joinRoutePath(g.prefix, pattern), g.host, fx, g.stack(fr))
+}
+
+// stack links the group middlewares in front of the supplied chain, returning the head of the chain
+func (g *RouteGroup) stack(fr FlatChains) FlatChains {
+	if len(g.middleware) == 0 {
+		return fr
+	}
+
+	head := NewFlatChain(g.middleware[0], g.router.Log)
+	for _, mw := range g.middleware[1:] {
+		head.Chain(NewFlatChain(mw, g.router.Log))
+	}
+
+	head.Chain(fr)
+	return head
+}
+
+// joinRoutePath joins a route prefix and a pattern into a single route pattern
+func joinRoutePath(prefix, pattern string) string {
+	pattern = cleanRoutePath(pattern)
+
+	if pattern == "/" {
+		return cleanRoutePath(prefix)
+	}
+
+	return strings.TrimRight(prefix, "/") + pattern
+}
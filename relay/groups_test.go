@@ -0,0 +1,54 @@
+package relay
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRouteGroup(t *testing.T) {
+	router := NewChainRouter(nil, nil)
+
+	var hits []string
+
+	mark := func(name string) FlatHandler {
+		return func(c *Context, next NextHandler) {
+			hits = append(hits, name)
+			next(c)
+		}
+	}
+
+	api := router.Group("/api", mark("api"))
+	v1 := api.Group("/v1/", mark("v1"))
+
+	v1.Rule("get", "/users/:id", func(c *Context, next NextHandler) {
+		hits = append(hits, "users")
+		expect(t, c.Get("id"), "20")
+	})
+
+	v1.BareRule("get", "/", func(w http.ResponseWriter, r *http.Request, c Collector) {
+		hits = append(hits, "index")
+	})
+
+	router.Rule("get", "/users/:id", mark("plain"))
+
+	serve := func(path string) {
+		hits = nil
+		req, _ := http.NewRequest("GET", "http://localhost:3000"+path, nil)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	serve("/api/v1/users/20")
+	expect(t, len(hits), 3)
+	expect(t, hits[0], "api")
+	expect(t, hits[1], "v1")
+	expect(t, hits[2], "users")
+
+	serve("/api/v1")
+	expect(t, len(hits), 3)
+	expect(t, hits[2], "index")
+
+	serve("/users/20")
+	expect(t, len(hits), 1)
+	expect(t, hits[0], "plain")
+}
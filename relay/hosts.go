@@ -0,0 +1,218 @@
+package relay
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// hosts.go provides host and subdomain matching for the ChainRouter
+
+// hostPattern provides a compiled host pattern, where each label can be a literal,
+// a '{name}' or '{name:regex}' parameter or a '*' wildcard
+type hostPattern struct {
+	pattern string
+	rx      *regexp.Regexp
+}
+
+// hostMount provides a ChainRouter mounted for a host pattern
+type hostMount struct {
+	host   *hostPattern
+	router *ChainRouter
+}
+
+// Host returns a new RouteGroup whose rules only match requests made to hosts matching the pattern, parameters in the host are added to the path parameters
+//
+//   tenant := router.Host("{tenant}.example.com")
+//   tenant.Rule("get", "/", func(c *Context, next NextHandler) {
+//     c.Get("tenant")
+//   })
+func (r *ChainRouter) Host(pattern string, mw ...FlatHandler) *RouteGroup {
+	g := r.Group("/", mw...)
+	g.host = mustHostPattern(pattern)
+	return g
+}
+
+// MountHost mounts a ChainRouter which handles every request to a host matching the pattern, parameters in the host are passed to the routes of the mounted router
+func (r *ChainRouter) MountHost(pattern string, sub *ChainRouter) {
+	mo := &hostMount{host: mustHostPattern(pattern), router: sub}
+
+	r.wg.Lock()
+	defer r.wg.Unlock()
+	r.hosts = append(r.hosts, mo)
+}
+
+// mustHostPattern returns a new hostPattern, panicking if the pattern is invalid
+func mustHostPattern(pattern string) *hostPattern {
+	hp, err := newHostPattern(pattern)
+	if err != nil {
+		panic(err)
+	}
+	return hp
+}
+
+// newHostPattern compiles the host pattern, a port in the pattern is ignored as
+// hosts are matched without their ports. A leading '*' label matches one or more
+// labels while every other '*' matches a single label
+func newHostPattern(pattern string) (*hostPattern, error) {
+	labels, err := hostLabels(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(labels) == 1 && labels[0] == "" {
+		return nil, NewCustomError("HostPattern", "host pattern can not be empty")
+	}
+
+	var parts []string
+
+	for i, label := range labels {
+		switch {
+		case label == "*" && i == 0:
+			parts = append(parts, `.+`)
+		case label == "*":
+			parts = append(parts, `[^.]+`)
+		case strings.HasPrefix(label, "{") && strings.HasSuffix(label, "}"):
+			body := label[1 : len(label)-1]
+			name, expr := body, `[^.]+`
+
+			if ix := strings.Index(body, ":"); ix != -1 {
+				name, expr = body[:ix], body[ix+1:]
+			}
+
+			if name == "" {
+				return nil, NewCustomError("HostPattern", fmt.Sprintf("host pattern %q has an unnamed parameter", pattern))
+			}
+
+			parts = append(parts, fmt.Sprintf("(?P<%s>%s)", name, expr))
+		default:
+			parts = append(parts, regexp.QuoteMeta(label))
+		}
+	}
+
+	rx, err := regexp.Compile(`^(?i)` + strings.Join(parts, `\.`) + `$`)
+	if err != nil {
+		return nil, err
+	}
+
+	return &hostPattern{pattern: pattern, rx: rx}, nil
+}
+
+// hostLabels splits the host pattern into its labels, ignoring the dots and colons
+// within parameters so their expressions are kept whole, and drops a trailing port
+func hostLabels(pattern string) ([]string, error) {
+	var labels []string
+	var depth, start int
+
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth < 0 {
+				return nil, NewCustomError("HostPattern", fmt.Sprintf("host pattern %q has an unbalanced '}'", pattern))
+			}
+		case '.':
+			if depth == 0 {
+				labels = append(labels, pattern[start:i])
+				start = i + 1
+			}
+		case ':':
+			if depth == 0 && isDigits(pattern[i+1:]) {
+				return append(labels, pattern[start:i]), nil
+			}
+		}
+	}
+
+	if depth != 0 {
+		return nil, NewCustomError("HostPattern", fmt.Sprintf("host pattern %q has an unbalanced '{'", pattern))
+	}
+
+	return append(labels, pattern[start:]), nil
+}
+
+// isDigits returns true/false if the value is made of one or more digits
+func isDigits(val string) bool {
+	if val == "" {
+		return false
+	}
+
+	for _, r := range val {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// match returns true/false if the host matches the pattern and the captured parameters
+func (h *hostPattern) match(host string) (bool, Collector) {
+	found := h.rx.FindStringSubmatch(host)
+	if found == nil {
+		return false, nil
+	}
+
+	params := make(Collector)
+	for i, name := range h.rx.SubexpNames() {
+		if name != "" {
+			params.Set(name, found[i])
+		}
+	}
+
+	return true, params
+}
+
+// requestHost returns the host of the request without its port
+func requestHost(rq *http.Request) string {
+	host := rq.Host
+	if host == "" && rq.URL != nil {
+		host = rq.URL.Host
+	}
+	return stripPort(strings.ToLower(host))
+}
+
+// stripPort removes the port from a host if present
+func stripPort(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h
+	}
+	return host
+}
+
+// filterHosts removes the matches whose host pattern does not match the host,
+// adding the host and supplied parameters to the parameters of the others
+func filterHosts(matches []routeMatch, host string, params Collector) []routeMatch {
+	filtered := matches[:0]
+
+	for _, ra := range matches {
+		var hp Collector
+
+		if ra.route.host != nil {
+			ok, co := ra.route.host.match(host)
+			if !ok {
+				continue
+			}
+			hp = co
+		}
+
+		ra.params = mergeParams(mergeParams(params, hp), ra.params)
+		filtered = append(filtered, ra)
+	}
+
+	return filtered
+}
+
+// mergeParams returns a new Collector with the values of both, the second taking precedence
+func mergeParams(first, second Collector) Collector {
+	if len(first) == 0 {
+		return second
+	}
+
+	co := first.Clone()
+	co.Copy(second)
+	return co
+}
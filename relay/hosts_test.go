@@ -0,0 +1,93 @@
+package relay
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/influx6/flux"
+)
+
+func TestHostRouting(t *testing.T) {
+	router := NewChainRouter(nil, nil)
+
+	var hits []string
+
+	tenant := router.Host("{tenant}.example.com")
+	tenant.Rule("get", "/users/:id", func(c *Context, next NextHandler) {
+		hits = append(hits, "tenant")
+		expect(t, c.Get("tenant"), "acme")
+		expect(t, c.Get("id"), "20")
+	})
+
+	router.Rule("get", "/users/:id", func(c *Context, next NextHandler) {
+		hits = append(hits, "any")
+	})
+
+	admin := NewChainRouter(func(w http.ResponseWriter, r *http.Request, params Collector) {
+		hits = append(hits, "fail:"+params.Get("region").(string))
+	}, nil)
+	admin.Rule("get", "/users/:id", func(c *Context, next NextHandler) {
+		hits = append(hits, "admin")
+		expect(t, c.Get("region"), "eu")
+	})
+
+	router.MountHost("admin.{region:[a-z]{2}}.example.com", admin)
+
+	serve := func(host, path string) {
+		hits = nil
+		req, _ := http.NewRequest("GET", "http://"+host+path, nil)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	serve("acme.example.com:8080", "/users/20")
+	expect(t, len(hits), 2)
+	expect(t, hits[0], "tenant")
+	expect(t, hits[1], "any")
+
+	serve("example.org", "/users/20")
+	expect(t, len(hits), 1)
+	expect(t, hits[0], "any")
+
+	serve("ADMIN.eu.example.com", "/users/20")
+	expect(t, len(hits), 1)
+	expect(t, hits[0], "admin")
+
+	//the fail handler of a mounted router gets the parameters of the host
+	serve("admin.us.example.com", "/missing")
+	expect(t, len(hits), 1)
+	expect(t, hits[0], "fail:us")
+
+	wild := mustHostPattern("*.example.com")
+	ok, _ := wild.match("a.b.example.com")
+	expect(t, ok, true)
+
+	ok, _ = wild.match("example.com")
+	expect(t, ok, false)
+
+	//parameter expressions keep their colons, dots and alternations
+	id := mustHostPattern(`{id:\d+}.example.com`)
+	ok, params := id.match("42.example.com")
+	expect(t, ok, true)
+	expect(t, params.Get("id"), "42")
+
+	ok, _ = id.match("abc.example.com")
+	expect(t, ok, false)
+
+	region := mustHostPattern("{r:eu|us}.example.com:8080")
+	ok, params = region.match("us.example.com")
+	expect(t, ok, true)
+	expect(t, params.Get("r"), "us")
+
+	ok, _ = region.match("asia.example.com")
+	expect(t, ok, false)
+
+	dotted := mustHostPattern("{name:[a-z.]+}.example.com")
+	ok, params = dotted.match("a.b.example.com")
+	expect(t, ok, true)
+	expect(t, params.Get("name"), "a.b")
+
+	if _, err := newHostPattern("{id.example.com"); err == nil {
+		flux.FatalFailed(t, "Expected unbalanced host pattern to fail")
+	}
+}
@@ -0,0 +1,94 @@
+package relay
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"runtime"
+	"strings"
+	"text/tabwriter"
+)
+
+// RouteInfo provides the details of a rule registered on a ChainRouter
+type RouteInfo struct {
+	Methods []string
+	Pattern string
+	Host    string
+	Name    string
+	Handler string
+}
+
+// Routes returns the details of every rule registered on the router in the order they were added, including the rules of routers mounted with MountHost
+func (r *ChainRouter) Routes() []RouteInfo {
+	var routes []RouteInfo
+
+	r.wg.RLock()
+	defer r.wg.RUnlock()
+
+	for _, mo := range r.hosts {
+		for _, info := range mo.router.Routes() {
+			if info.Host == "" {
+				info.Host = mo.host.pattern
+			}
+			routes = append(routes, info)
+		}
+	}
+
+	for _, cr := range r.paths {
+		info := RouteInfo{
+			Methods: cr.Methods,
+			Pattern: cr.pattern,
+			Name:    cr.Name,
+			Handler: handlerName(cr.handler),
+		}
+
+		if cr.host != nil {
+			info.Host = cr.host.pattern
+		}
+
+		routes = append(routes, info)
+	}
+
+	return routes
+}
+
+// PrintRoutes writes the registered routes of the router as a table into the writer
+func (r *ChainRouter) PrintRoutes(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "METHODS\tPATTERN\tHOST\tNAME\tHANDLER")
+
+	for _, info := range r.Routes() {
+		methods := "*"
+		if len(info.Methods) > 0 {
+			methods = strings.ToUpper(strings.Join(info.Methods, ","))
+		}
+
+		host := info.Host
+		if host == "" {
+			host = "*"
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", methods, info.Pattern, host, info.Name, info.Handler)
+	}
+
+	return tw.Flush()
+}
+
+// handlerName returns the name of the function used as a route handler
+func handlerName(fx interface{}) string {
+	if fx == nil {
+		return ""
+	}
+
+	val := reflect.ValueOf(fx)
+	if val.Kind() != reflect.Func || val.IsNil() {
+		return fmt.Sprintf("%T", fx)
+	}
+
+	if fn := runtime.FuncForPC(val.Pointer()); fn != nil {
+		return fn.Name()
+	}
+
+	return fmt.Sprintf("%T", fx)
+}
@@ -0,0 +1,42 @@
+package relay
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/influx6/flux"
+)
+
+func TestRouterRoutes(t *testing.T) {
+	router := NewChainRouter(nil, nil)
+
+	router.NamedRule("user", "get head", "/users/:id", IdentityCall)
+	router.Host("{tenant}.example.com").Rule("post", "/users", nil)
+
+	admin := NewChainRouter(nil, nil)
+	admin.BareRule("", "/stats", nil)
+	router.MountHost("admin.example.com", admin)
+
+	routes := router.Routes()
+	expect(t, len(routes), 3)
+
+	expect(t, routes[0].Pattern, "/stats")
+	expect(t, routes[0].Host, "admin.example.com")
+
+	expect(t, routes[1].Pattern, "/users/:id")
+	expect(t, routes[1].Name, "user")
+	expect(t, strings.Join(routes[1].Methods, " "), "get head")
+	expect(t, routes[1].Handler, "github.com/influx6/relay/relay.IdentityCall")
+
+	expect(t, routes[2].Host, "{tenant}.example.com")
+
+	var buf bytes.Buffer
+	if err := router.PrintRoutes(&buf); err != nil {
+		flux.FatalFailed(t, "Unable to print routes: %s", err)
+	}
+
+	if !strings.Contains(buf.String(), "GET,HEAD") {
+		flux.FatalFailed(t, "Expected methods in routes table: %s", buf.String())
+	}
+}
@@ -0,0 +1,254 @@
+package relay
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+)
+
+// jsonstream.go provides encoders which write json arrays and newline delimited
+// json item by item from a channel or Iterator, flushing each item so large
+// results are never held in memory
+//
+//   rows := make(chan *User)
+//   go exportUsers(c.Req.Context(), rows) // closes rows once done
+//   c.NDJSON(200, rows)
+//
+// Streams stop receiving from channels once the client disconnects, so channel
+// producers must also stop on the request context or block on their send forever
+//
+//   func exportUsers(ctx context.Context, rows chan<- *User) {
+//     defer close(rows)
+//     for _, user := range users {
+//       select {
+//       case rows <- user:
+//       case <-ctx.Done():
+//         return
+//       }
+//     }
+//   }
+
+// ContentNDJSON header value for newline delimited json
+const ContentNDJSON = "application/x-ndjson"
+
+// ErrStreamStopped is returned when a stream is stopped before its source is done, usually due to the client disconnecting
+var ErrStreamStopped = errors.New("stream stopped before its source was done")
+
+// ErrInvalidStreamSource is returned when a stream source is neither a receivable channel nor an Iterator
+var ErrInvalidStreamSource = errors.New("stream source must be a channel or Iterator")
+
+// Iterator provides a function type returning the next item of a stream, it
+// returns false once there are no more items
+type Iterator func() (interface{}, bool, error)
+
+// JSONStream provides the source of the items written by the JSONArrayEncoder and NDJSONEncoder
+type JSONStream struct {
+	*Head
+	//Source provides the items as a channel of any element type or an Iterator, channels must be closed once done
+	//and their producers must stop sending once Done is closed as the channel is no longer received from
+	Source interface{}
+	//Done stops the stream when closed, the Context helpers set it to the request context's Done channel
+	Done <-chan struct{}
+}
+
+// JSONArrayRender returns a JSONStream for rendering the source as a json array
+func JSONArrayRender(status int, source interface{}, done <-chan struct{}) *JSONStream {
+	return &JSONStream{
+		Source: source,
+		Done:   done,
+		Head: &Head{
+			Status:  status,
+			Content: ContentJSON,
+		},
+	}
+}
+
+// NDJSONRender returns a JSONStream for rendering the source as newline delimited json
+func NDJSONRender(status int, source interface{}, done <-chan struct{}) *JSONStream {
+	return &JSONStream{
+		Source: source,
+		Done:   done,
+		Head: &Head{
+			Status:  status,
+			Content: ContentNDJSON,
+		},
+	}
+}
+
+// JSONArrayEncoder provides an encoder which writes a JSONStream as a json array, flushing after each item
+var JSONArrayEncoder = NewEncoder(func(w io.Writer, d interface{}) (int, error) {
+	jso, ok := d.(*JSONStream)
+
+	if !ok {
+		return 0, NewCustomError("JSONArrayEncoder", "Wrong type,expected JSONStream type")
+	}
+
+	sw := &streamWriter{w: w}
+	sw.write([]byte("["))
+
+	var count int
+	err := streamItems(jso.Source, jso.Done, func(item interface{}) error {
+		data, err := json.Marshal(item)
+		if err != nil {
+			return err
+		}
+
+		if count > 0 {
+			sw.write([]byte(","))
+		}
+		count++
+
+		sw.write(data)
+		return sw.flush()
+	})
+
+	if err != nil {
+		return sw.size, err
+	}
+
+	sw.write([]byte("]"))
+	return sw.size, sw.flush()
+})
+
+// NDJSONEncoder provides an encoder which writes a JSONStream as newline delimited json, flushing after each item
+var NDJSONEncoder = NewEncoder(func(w io.Writer, d interface{}) (int, error) {
+	jso, ok := d.(*JSONStream)
+
+	if !ok {
+		return 0, NewCustomError("NDJSONEncoder", "Wrong type,expected JSONStream type")
+	}
+
+	sw := &streamWriter{w: w}
+
+	err := streamItems(jso.Source, jso.Done, func(item interface{}) error {
+		data, err := json.Marshal(item)
+		if err != nil {
+			return err
+		}
+
+		sw.write(append(data, '\n'))
+		return sw.flush()
+	})
+
+	return sw.size, err
+})
+
+// JSONArray writes the head and streams the items of the source as a json array,
+// stopping with ErrStreamStopped if the client disconnects. Channel producers
+// must stop sending once the request context is done
+func (c *Context) JSONArray(status int, source interface{}) error {
+	return c.streamJSON(JSONArrayEncoder, JSONArrayRender(status, source, c.Req.Context().Done()))
+}
+
+// NDJSON writes the head and streams the items of the source as newline delimited json,
+// stopping with ErrStreamStopped if the client disconnects. Channel producers
+// must stop sending once the request context is done
+func (c *Context) NDJSON(status int, source interface{}) error {
+	return c.streamJSON(NDJSONEncoder, NDJSONRender(status, source, c.Req.Context().Done()))
+}
+
+// streamJSON writes the head of the stream and encodes it directly into the response
+func (c *Context) streamJSON(enc Encoder, jso *JSONStream) error {
+	if err := BasicHeadEncoder.Encode(c, jso.Head); err != nil {
+		return err
+	}
+
+	_, err := enc.Encode(c.Res, jso)
+	return err
+}
+
+// streamWriter provides a writer which keeps the first write error and flushes
+// the underlying writer if its a http.Flusher
+type streamWriter struct {
+	w    io.Writer
+	size int
+	err  error
+}
+
+// write writes the data unless a previous write failed
+func (s *streamWriter) write(data []byte) {
+	if s.err != nil {
+		return
+	}
+
+	n, err := s.w.Write(data)
+	s.size += n
+	s.err = err
+}
+
+// flush flushes the writer and returns any write error
+func (s *streamWriter) flush() error {
+	if s.err != nil {
+		return s.err
+	}
+
+	if fl, ok := s.w.(http.Flusher); ok {
+		fl.Flush()
+	}
+
+	return nil
+}
+
+// streamItems calls each for every item from the source until it is done, the
+// done channel is closed or each returns an error
+func streamItems(source interface{}, done <-chan struct{}, each func(interface{}) error) error {
+	switch src := source.(type) {
+	case Iterator:
+		return iterateItems(src, done, each)
+	case func() (interface{}, bool, error):
+		return iterateItems(src, done, each)
+	}
+
+	rv := reflect.ValueOf(source)
+	if rv.Kind() != reflect.Chan || rv.Type().ChanDir()&reflect.RecvDir == 0 {
+		return ErrInvalidStreamSource
+	}
+
+	cases := []reflect.SelectCase{{Dir: reflect.SelectRecv, Chan: rv}}
+	if done != nil {
+		cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(done)})
+	}
+
+	for {
+		chosen, item, ok := reflect.Select(cases)
+
+		if chosen == 1 {
+			return ErrStreamStopped
+		}
+
+		if !ok {
+			return nil
+		}
+
+		if err := each(item.Interface()); err != nil {
+			return err
+		}
+	}
+}
+
+// iterateItems calls each for every item returned by the iterator, checking the
+// done channel before retrieving each item
+func iterateItems(next Iterator, done <-chan struct{}, each func(interface{}) error) error {
+	for {
+		select {
+		case <-done:
+			return ErrStreamStopped
+		default:
+		}
+
+		item, ok, err := next()
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			return nil
+		}
+
+		if err := each(item); err != nil {
+			return err
+		}
+	}
+}
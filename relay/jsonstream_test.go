@@ -0,0 +1,125 @@
+package relay
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/influx6/flux"
+)
+
+func TestJSONStreams(t *testing.T) {
+	req, _ := http.NewRequest("GET", "http://localhost:3000/export", nil)
+
+	items := make(chan int, 3)
+	items <- 1
+	items <- 2
+	items <- 3
+	close(items)
+
+	rec := httptest.NewRecorder()
+	c := NewContext(rec, req)
+
+	if err := c.JSONArray(http.StatusOK, items); err != nil {
+		flux.FatalFailed(t, "Unable to stream json array: %s", err)
+	}
+
+	expect(t, rec.Header().Get("Content-Type"), ContentJSON)
+	expect(t, rec.Body.String(), "[1,2,3]")
+	expect(t, rec.Flushed, true)
+
+	var count int
+	users := Iterator(func() (interface{}, bool, error) {
+		if count == 2 {
+			return nil, false, nil
+		}
+		count++
+		return map[string]int{"id": count}, true, nil
+	})
+
+	rec = httptest.NewRecorder()
+	c = NewContext(rec, req)
+
+	if err := c.NDJSON(http.StatusOK, users); err != nil {
+		flux.FatalFailed(t, "Unable to stream ndjson: %s", err)
+	}
+
+	expect(t, rec.Header().Get("Content-Type"), ContentNDJSON)
+	expect(t, rec.Body.String(), "{\"id\":1}\n{\"id\":2}\n")
+
+	empty := make(chan string)
+	close(empty)
+
+	var buf bytes.Buffer
+	if _, err := JSONArrayEncoder.Encode(&buf, JSONArrayRender(http.StatusOK, empty, nil)); err != nil {
+		flux.FatalFailed(t, "Unable to encode empty json array: %s", err)
+	}
+	expect(t, buf.String(), "[]")
+
+	//streams stop once the client disconnects
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	rec = httptest.NewRecorder()
+	c = NewContext(rec, req.WithContext(ctx))
+
+	if err := c.NDJSON(http.StatusOK, make(chan int)); err != ErrStreamStopped {
+		flux.FatalFailed(t, "Expected ErrStreamStopped: %s", err)
+	}
+
+	if err := c.NDJSON(http.StatusOK, 20); err != ErrInvalidStreamSource {
+		flux.FatalFailed(t, "Expected ErrInvalidStreamSource: %s", err)
+	}
+}
+
+func TestJSONStreamProducerStops(t *testing.T) {
+	exited := make(chan struct{})
+	started := make(chan struct{})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c := NewContext(w, r)
+		rows := make(chan int)
+
+		//the producer stops on the request context as the stream stops receiving
+		go func(ctx context.Context) {
+			defer close(exited)
+			defer close(rows)
+
+			for i := 0; ; i++ {
+				select {
+				case rows <- i:
+				case <-ctx.Done():
+					return
+				}
+
+				if i == 1 {
+					close(started)
+				}
+			}
+		}(r.Context())
+
+		c.NDJSON(http.StatusOK, rows)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequest("GET", server.URL, nil)
+
+	res, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		flux.FatalFailed(t, "Unable to request stream: %s", err)
+	}
+
+	<-started
+	cancel()
+	res.Body.Close()
+
+	select {
+	case <-exited:
+	case <-time.After(5 * time.Second):
+		flux.FatalFailed(t, "Expected the producer to exit once the client disconnected")
+	}
+}
@@ -1,6 +1,7 @@
 package relay
 
 import (
+	"context"
 	"errors"
 	"log"
 	"net/http"
@@ -19,6 +20,10 @@ type Context struct {
 	Req *http.Request
 	Res ResponseWriter
 	Log *log.Logger
+	//BodyLimit sets the maximum size of the request body read by the message decoders, DefaultBodyLimit is used when zero
+	BodyLimit int64
+	//Templates provides the templates used by Render, the router's Templates are used when nil
+	Templates *Templates
 	// sock *SocketWorker
 }
 
@@ -36,7 +41,7 @@ func NewContextWith(res http.ResponseWriter, req *http.Request, loga *log.Logger
 	cx := Context{
 		SyncCollector: NewSyncCollector(),
 		Req:           req,
-		Res:           NewResponseWriter(res),
+		Res:           NewRequestResponseWriter(res, req),
 		Log:           loga,
 	}
 	return &cx
@@ -60,6 +65,51 @@ type NextHandler func(*Context)
 // FlatHandler provides a handler for flatchain
 type FlatHandler func(*Context, NextHandler)
 
+// ErrFlatHandler provides a handler for flatchain which returns an error, a
+// returned error stops the chain unless next was already called and is passed
+// to the ErrorHandler of the router serving the request
+type ErrFlatHandler func(*Context, NextHandler) error
+
+// ErrorHandler provides a function type for handling the errors returned by ErrFlatHandlers
+type ErrorHandler func(*Context, error)
+
+// DefaultErrorHandler handles the errors returned by ErrFlatHandlers outside of a
+// ChainRouter with an ErrorHandler, rendering them as problem details
+var DefaultErrorHandler ErrorHandler = ProblemHandler(false)
+
+// errorHandlerKey provides the request context key used by the ChainRouter to
+// make its ErrorHandler available to the Context
+type errorHandlerKey struct{}
+
+// ErrFlat returns a FlatHandler which calls the ErrFlatHandler and passes any
+// returned error to Context.HandleError
+//
+//   app.Rule("get", "/users/:id", relay.ErrFlat(func(c *relay.Context, next relay.NextHandler) error {
+//     return c.JSON(200, user)
+//   }))
+func ErrFlat(fx ErrFlatHandler) FlatHandler {
+	return func(c *Context, next NextHandler) {
+		if err := fx(c, next); err != nil {
+			c.HandleError(err)
+		}
+	}
+}
+
+// HandleError passes the error to the ErrorHandler of the router serving the
+// request or the DefaultErrorHandler if it has none
+func (c *Context) HandleError(err error) {
+	if eh, ok := c.Req.Context().Value(errorHandlerKey{}).(ErrorHandler); ok && eh != nil {
+		eh(c, err)
+		return
+	}
+	DefaultErrorHandler(c, err)
+}
+
+// withErrorHandler returns the request carrying the ErrorHandler for Context.HandleError
+func withErrorHandler(rq *http.Request, eh ErrorHandler) *http.Request {
+	return rq.WithContext(context.WithValue(rq.Context(), errorHandlerKey{}, eh))
+}
+
 // FlatChain provides a simple middleware like
 type FlatChain struct {
 	op   FlatHandler
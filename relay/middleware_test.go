@@ -0,0 +1,62 @@
+package relay
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestErrFlatHandler(t *testing.T) {
+	errDenied := errors.New("denied")
+
+	var handled error
+	var reached bool
+
+	router := NewChainRouter(nil, nil)
+	router.ErrorHandler = func(c *Context, err error) {
+		handled = err
+		c.Res.WriteHeader(http.StatusForbidden)
+	}
+
+	router.Rule("get", "/admin", ErrFlat(func(c *Context, next NextHandler) error {
+		return errDenied
+	})).ChainFlat(func(c *Context, next NextHandler) {
+		reached = true
+		next(c)
+	})
+
+	router.Rule("get", "/users", ErrFlat(func(c *Context, next NextHandler) error {
+		next(c)
+		return nil
+	})).ChainFlat(ErrFlat(func(c *Context, next NextHandler) error {
+		return c.Text(http.StatusOK, "users")
+	}))
+
+	req, _ := http.NewRequest("GET", "http://localhost:3000/admin", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	expect(t, rec.Code, http.StatusForbidden)
+	expect(t, handled, errDenied)
+	expect(t, reached, false)
+
+	req, _ = http.NewRequest("GET", "http://localhost:3000/users", nil)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	expect(t, rec.Code, http.StatusOK)
+	expect(t, rec.Body.String(), "users")
+
+	//errors outside of a router go to the DefaultErrorHandler
+	chain := FlatRoute("get", "/missing", ErrFlat(func(c *Context, next NextHandler) error {
+		return NewHTTPError(http.StatusNotFound, "missing")
+	}), nil)
+
+	req, _ = http.NewRequest("GET", "http://localhost:3000/missing", nil)
+	rec = httptest.NewRecorder()
+	chain.ServeHTTP(rec, req)
+
+	expect(t, rec.Code, http.StatusNotFound)
+	expect(t, rec.Header().Get("Content-Type"), ContentProblemJSON)
+}
@@ -0,0 +1,174 @@
+package relay
+
+import (
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+)
+
+// mime.go provides the media types of files served by FS, looked up by their
+// extension and sniffed from their content when unknown
+//
+//   fs := relay.NewFS(http.Dir("./static"), "static")
+//   fs.Mimes = relay.Mimes.Extend().Add(".tmpl", "text/html")
+
+// DefaultCharset provides the charset added to textual media types
+const DefaultCharset = "utf-8"
+
+// DefaultMimeTypes provides the media types of the default MimeRegistry by file extension
+var DefaultMimeTypes = map[string]string{
+	".txt":         "text/plain",
+	".text":        "text/plain",
+	".html":        "text/html",
+	".htm":         "text/html",
+	".css":         "text/css",
+	".csv":         "text/csv",
+	".js":          "text/javascript",
+	".mjs":         "text/javascript",
+	".json":        "application/json",
+	".map":         "application/json",
+	".jsonld":      "application/ld+json",
+	".webmanifest": "application/manifest+json",
+	".xml":         "application/xml",
+	".markdown":    "text/markdown",
+	".md":          "text/markdown",
+	".haml":        "text/haml",
+	".erb":         "template/erb",
+	".svg":         "image/svg+xml",
+	".png":         "image/png",
+	".jpg":         "image/jpeg",
+	".jpeg":        "image/jpeg",
+	".gif":         "image/gif",
+	".webp":        "image/webp",
+	".avif":        "image/avif",
+	".ico":         "image/x-icon",
+	".woff":        "font/woff",
+	".woff2":       "font/woff2",
+	".ttf":         "font/ttf",
+	".otf":         "font/otf",
+	".wasm":        "application/wasm",
+	".pdf":         "application/pdf",
+	".zip":         "application/zip",
+	".gz":          "application/gzip",
+	".mp3":         "audio/mpeg",
+	".ogg":         "audio/ogg",
+	".wav":         "audio/wav",
+	".mp4":         "video/mp4",
+	".webm":        "video/webm",
+}
+
+// textualTypes provides the non text/* media types which carry a charset, the
+// legacy 'application/javascript' is kept for registries which still use it
+var textualTypes = map[string]bool{
+	"application/javascript":    true,
+	"application/json":          true,
+	"application/ld+json":       true,
+	"application/manifest+json": true,
+	"application/xml":           true,
+	"image/svg+xml":             true,
+}
+
+// Mimes provides the default MimeRegistry used by FS
+var Mimes = NewMimeRegistry()
+
+// MimeRegistry provides the media types of files by their extension, registries
+// created with Extend fall back to their parent for unknown extensions
+type MimeRegistry struct {
+	//Charset sets the charset added to textual media types, none is added when empty
+	Charset string
+
+	rw     sync.RWMutex
+	types  map[string]string
+	parent *MimeRegistry
+}
+
+// NewMimeRegistry returns a new MimeRegistry with the DefaultMimeTypes
+func NewMimeRegistry() *MimeRegistry {
+	mr := &MimeRegistry{
+		Charset: DefaultCharset,
+		types:   make(map[string]string),
+	}
+
+	for ext, media := range DefaultMimeTypes {
+		mr.types[ext] = media
+	}
+
+	return mr
+}
+
+// Extend returns a new MimeRegistry overriding the media types of this registry
+func (m *MimeRegistry) Extend() *MimeRegistry {
+	return &MimeRegistry{
+		Charset: m.Charset,
+		types:   make(map[string]string),
+		parent:  m,
+	}
+}
+
+// Add sets the media type of the extension, i.e '.tmpl' or 'tmpl'
+func (m *MimeRegistry) Add(ext, media string) *MimeRegistry {
+	if !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+
+	m.rw.Lock()
+	m.types[strings.ToLower(ext)] = media
+	m.rw.Unlock()
+
+	return m
+}
+
+// Lookup returns the media type of the file from its extension, using the registry,
+// its parents and then the system's types. It returns false if the extension is unknown
+func (m *MimeRegistry) Lookup(name string) (string, bool) {
+	ext := strings.ToLower(path.Ext(name))
+	if ext == "" {
+		return "", false
+	}
+
+	for mr := m; mr != nil; mr = mr.parent {
+		mr.rw.RLock()
+		media, ok := mr.types[ext]
+		mr.rw.RUnlock()
+
+		if ok {
+			return m.withCharset(media), true
+		}
+	}
+
+	if media := mime.TypeByExtension(ext); media != "" {
+		return m.withCharset(media), true
+	}
+
+	return "", false
+}
+
+// TypeOf returns the media type of the file from its extension, sniffing it from
+// the content when unknown. Empty content of an unknown type is 'application/octet-stream'
+func (m *MimeRegistry) TypeOf(name string, content []byte) string {
+	if media, ok := m.Lookup(name); ok {
+		return media
+	}
+
+	if len(content) == 0 {
+		return "application/octet-stream"
+	}
+
+	return http.DetectContentType(content)
+}
+
+// withCharset adds the Charset to textual media types which have none
+func (m *MimeRegistry) withCharset(media string) string {
+	if m.Charset == "" || strings.Contains(media, "charset=") {
+		return media
+	}
+
+	base := strings.ToLower(strings.TrimSpace(strings.Split(media, ";")[0]))
+	if !strings.HasPrefix(base, "text/") && !textualTypes[base] {
+		return media
+	}
+
+	return media + "; charset=" + m.Charset
+}
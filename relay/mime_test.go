@@ -0,0 +1,82 @@
+package relay
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/influx6/flux"
+)
+
+func TestMimeRegistry(t *testing.T) {
+	mimes := NewMimeRegistry()
+
+	media, ok := mimes.Lookup("photo.GIF")
+	expect(t, ok, true)
+	expect(t, media, "image/gif")
+
+	media, _ = mimes.Lookup("app.js")
+	expect(t, media, "text/javascript; charset=utf-8")
+
+	media, _ = mimes.Lookup("lib.mjs")
+	expect(t, media, "text/javascript; charset=utf-8")
+
+	media, _ = mimes.Lookup("fonts/icons.woff2")
+	expect(t, media, "font/woff2")
+
+	media, _ = mimes.Lookup("main.wasm")
+	expect(t, media, "application/wasm")
+
+	_, ok = mimes.Lookup("README")
+	expect(t, ok, false)
+
+	expect(t, mimes.TypeOf("README", []byte("\x89PNG\x0D\x0A\x1A\x0A")), "image/png")
+	expect(t, mimes.TypeOf("README", nil), "application/octet-stream")
+
+	//extended registries override their parent
+	custom := mimes.Extend().Add("tmpl", "text/html").Add(".js", "application/javascript")
+
+	media, _ = custom.Lookup("page.tmpl")
+	expect(t, media, "text/html; charset=utf-8")
+
+	media, _ = custom.Lookup("app.js")
+	expect(t, media, "application/javascript; charset=utf-8")
+
+	media, _ = custom.Lookup("photo.webp")
+	expect(t, media, "image/webp")
+
+	_, ok = mimes.Lookup("page.tmpl")
+	expect(t, ok, false)
+}
+
+func TestFSMimes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "relay-mimes")
+	if err != nil {
+		flux.FatalFailed(t, "Unable to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeTemplate(t, dir, "LICENSE", "MIT License")
+	writeTemplate(t, dir, "page.tmpl", "<b>page</b>")
+
+	fs := NewFS(http.Dir(dir), "")
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", "http://localhost:3000"+path, nil)
+		rec := httptest.NewRecorder()
+		fs.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := serve("/LICENSE")
+	expect(t, rec.Header().Get("Content-Type"), "text/plain; charset=utf-8")
+	expect(t, rec.Header().Get("X-Content-Type-Options"), "nosniff")
+	expect(t, rec.Body.String(), "MIT License")
+
+	fs.Mimes = Mimes.Extend().Add(".tmpl", "text/html")
+
+	rec = serve("/page.tmpl")
+	expect(t, rec.Header().Get("Content-Type"), "text/html; charset=utf-8")
+}
@@ -0,0 +1,198 @@
+package relay
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// negotiate.go provides content negotiation, picking the encoder used for a
+// response from the request's Accept header
+
+// ErrNotAcceptable is returned when no registered encoder matches the Accept header
+var ErrNotAcceptable = errors.New("no acceptable media type for the request")
+
+// JSONDataEncoder provides an encoder which renders any value as json using the JSONEncoder
+var JSONDataEncoder = NewEncoder(func(w io.Writer, d interface{}) (int, error) {
+	return JSONEncoder.Encode(w, JSONRender(http.StatusOK, d, false, false, false))
+})
+
+// XMLDataEncoder provides an encoder which renders any value as xml using the XMLEncoder
+var XMLDataEncoder = NewEncoder(func(w io.Writer, d interface{}) (int, error) {
+	return XMLEncoder.Encode(w, XMLRender(http.StatusOK, false, d, nil))
+})
+
+// TextDataEncoder provides an encoder which renders any value as text using the TextEncoder
+var TextDataEncoder = NewEncoder(func(w io.Writer, d interface{}) (int, error) {
+	return TextEncoder.Encode(w, TextRender(http.StatusOK, fmt.Sprint(d)))
+})
+
+// DefaultNegotiator provides a Negotiator for json, xml and text responses, json
+// is used when the request has no Accept header
+var DefaultNegotiator = NewNegotiator().
+	Add(ContentJSON, JSONDataEncoder).
+	Add(ContentXML, XMLDataEncoder).
+	Add("application/xml", XMLDataEncoder).
+	Add(ContentText, TextDataEncoder)
+
+// Negotiator provides a registry of media types and their encoders, used to
+// render a value in the format best matching the request's Accept header. The
+// encoders receive the value given to Render as is
+type Negotiator struct {
+	types    []string
+	encoders map[string]Encoder
+}
+
+// NewNegotiator returns a new Negotiator instance
+func NewNegotiator() *Negotiator {
+	return &Negotiator{encoders: make(map[string]Encoder)}
+}
+
+// Add registers the encoder for the media type, the first registered type is
+// used for requests without an Accept header
+func (n *Negotiator) Add(media string, enc Encoder) *Negotiator {
+	media = strings.ToLower(media)
+
+	if _, ok := n.encoders[media]; !ok {
+		n.types = append(n.types, media)
+	}
+
+	n.encoders[media] = enc
+	return n
+}
+
+// Select returns the registered media type and encoder best matching the Accept header
+func (n *Negotiator) Select(accept string) (string, Encoder, bool) {
+	if len(n.types) == 0 {
+		return "", nil, false
+	}
+
+	if strings.TrimSpace(accept) == "" {
+		return n.types[0], n.encoders[n.types[0]], true
+	}
+
+	ranges := parseAccept(accept)
+
+	var best string
+	var bestQ float64
+	var bestRank int
+
+	for _, media := range n.types {
+		q, rank := acceptQuality(ranges, media)
+
+		if q <= 0 {
+			continue
+		}
+
+		if q > bestQ || (q == bestQ && rank > bestRank) {
+			best, bestQ, bestRank = media, q, rank
+		}
+	}
+
+	if best == "" {
+		return "", nil, false
+	}
+
+	return best, n.encoders[best], true
+}
+
+// Render encodes the data with the encoder matching the request and writes it with
+// its status, Content-Type and a 'Vary: Accept' header. If no encoder matches, a
+// 406 is written and ErrNotAcceptable returned. Encoding errors are returned before
+// anything is written to the response
+func (n *Negotiator) Render(c *Context, status int, data interface{}) error {
+	headers := make(http.Header)
+	headers.Set("Vary", "Accept")
+
+	media, enc, ok := n.Select(c.Req.Header.Get("Accept"))
+
+	if !ok {
+		BasicHeadEncoder.Encode(c, &Head{
+			Status:  http.StatusNotAcceptable,
+			Content: ContentText,
+			Headers: headers,
+		})
+		c.Res.Write([]byte(strings.Join(n.types, ", ")))
+		return ErrNotAcceptable
+	}
+
+	return c.Respond(enc, &Head{
+		Status:  status,
+		Content: media,
+		Headers: headers,
+	}, data)
+}
+
+// acceptRange provides a single media range of an Accept header
+type acceptRange struct {
+	media string
+	q     float64
+}
+
+// parseAccept parses the media ranges and quality values of an Accept header
+func parseAccept(accept string) []acceptRange {
+	var ranges []acceptRange
+
+	for _, part := range strings.Split(accept, ",") {
+		fields := strings.Split(part, ";")
+		media := strings.ToLower(strings.TrimSpace(fields[0]))
+
+		if media == "" {
+			continue
+		}
+
+		if media == "*" {
+			media = "*/*"
+		}
+
+		q := 1.0
+		for _, param := range fields[1:] {
+			kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+			if len(kv) == 2 && strings.ToLower(strings.TrimSpace(kv[0])) == "q" {
+				if val, err := strconv.ParseFloat(strings.TrimSpace(kv[1]), 64); err == nil {
+					q = val
+				}
+			}
+		}
+
+		ranges = append(ranges, acceptRange{media: media, q: q})
+	}
+
+	return ranges
+}
+
+// acceptQuality returns the quality given to the media type by the most specific
+// matching media range and that range's specificity
+func acceptQuality(ranges []acceptRange, media string) (float64, int) {
+	var q float64
+	rank := -1
+
+	kind := media
+	if ix := strings.Index(media, "/"); ix != -1 {
+		kind = media[:ix]
+	}
+
+	for _, ar := range ranges {
+		var specific int
+
+		switch {
+		case ar.media == media:
+			specific = 2
+		case ar.media == kind+"/*":
+			specific = 1
+		case ar.media == "*/*":
+			specific = 0
+		default:
+			continue
+		}
+
+		if specific > rank {
+			rank, q = specific, ar.q
+		}
+	}
+
+	return q, rank
+}
@@ -0,0 +1,47 @@
+package relay
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/influx6/flux"
+)
+
+type negotiated struct {
+	XMLName struct{} `json:"-" xml:"user"`
+	Name    string   `json:"user" xml:"name"`
+}
+
+func TestNegotiator(t *testing.T) {
+	render := func(accept string) (*httptest.ResponseRecorder, error) {
+		req, _ := http.NewRequest("GET", "http://localhost:3000/users", nil)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		rec := httptest.NewRecorder()
+		err := DefaultNegotiator.Render(NewContext(rec, req), 200, negotiated{Name: "alex"})
+		return rec, err
+	}
+
+	rec, err := render("")
+	if err != nil {
+		flux.FatalFailed(t, "Unable to render: %s", err)
+	}
+	expect(t, rec.Header().Get("Content-Type"), ContentJSON)
+	expect(t, rec.Header().Get("Vary"), "Accept")
+	expect(t, rec.Body.String(), `{"user":"alex"}`)
+
+	rec, _ = render("text/html;q=0.9, application/xml;q=0.8, application/json;q=0.5")
+	expect(t, rec.Header().Get("Content-Type"), "application/xml")
+
+	rec, _ = render("text/*, application/json;q=0.2")
+	expect(t, rec.Header().Get("Content-Type"), ContentXML)
+
+	rec, _ = render("application/*;q=0.3, application/json;q=0")
+	expect(t, rec.Header().Get("Content-Type"), "application/xml")
+
+	rec, err = render("image/png")
+	expect(t, err, ErrNotAcceptable)
+	expect(t, rec.Code, http.StatusNotAcceptable)
+}
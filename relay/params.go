@@ -0,0 +1,406 @@
+package relay
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// params.go provides typed accessors for route parameters and query values on the Context
+
+// ErrParamNotFound is returned when a requested parameter or query value does not exist
+var ErrParamNotFound = errors.New("parameter not found")
+
+// ErrInvalidUUID is returned when a value is not a valid UUID
+var ErrInvalidUUID = errors.New("value is not a valid UUID")
+
+// ErrNotStructPointer is returned when a binding target is not a pointer to a struct
+var ErrNotStructPointer = errors.New("binding target must be a pointer to a struct")
+
+var uuidFormat = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// ParamError provides the details of a value which could not be converted
+type ParamError struct {
+	Source string
+	Name   string
+	Value  string
+	Err    error
+}
+
+// Error returns the error message
+func (p *ParamError) Error() string {
+	if p.Value == "" {
+		return fmt.Sprintf("%s %q: %s", p.Source, p.Name, p.Err)
+	}
+	return fmt.Sprintf("%s %q with value %q: %s", p.Source, p.Name, p.Value, p.Err)
+}
+
+// ParamErrors provides a list of ParamError returned when binding values into a struct
+type ParamErrors []*ParamError
+
+// Error returns the error messages joined together
+func (p ParamErrors) Error() string {
+	var msgs []string
+	for _, pe := range p {
+		msgs = append(msgs, pe.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Param returns the route parameter as a string, an empty string is returned if it does not exist
+func (c *Context) Param(name string) string {
+	val, _ := c.paramString(name)
+	return val
+}
+
+// ParamInt returns the route parameter as an int
+func (c *Context) ParamInt(name string) (int, error) {
+	val, err := c.ParamInt64(name)
+	return int(val), err
+}
+
+// ParamInt64 returns the route parameter as an int64
+func (c *Context) ParamInt64(name string) (int64, error) {
+	val, err := c.paramString(name)
+	if err != nil {
+		return 0, err
+	}
+
+	num, err := strconv.ParseInt(val, 10, 64)
+	if err != nil {
+		return 0, &ParamError{Source: "param", Name: name, Value: val, Err: err}
+	}
+
+	return num, nil
+}
+
+// ParamFloat returns the route parameter as a float64
+func (c *Context) ParamFloat(name string) (float64, error) {
+	val, err := c.paramString(name)
+	if err != nil {
+		return 0, err
+	}
+
+	num, err := strconv.ParseFloat(val, 64)
+	if err != nil {
+		return 0, &ParamError{Source: "param", Name: name, Value: val, Err: err}
+	}
+
+	return num, nil
+}
+
+// ParamBool returns the route parameter as a bool
+func (c *Context) ParamBool(name string) (bool, error) {
+	val, err := c.paramString(name)
+	if err != nil {
+		return false, err
+	}
+
+	state, err := strconv.ParseBool(val)
+	if err != nil {
+		return false, &ParamError{Source: "param", Name: name, Value: val, Err: err}
+	}
+
+	return state, nil
+}
+
+// ParamUUID returns the route parameter validated as a UUID in its lowercase form
+func (c *Context) ParamUUID(name string) (string, error) {
+	val, err := c.paramString(name)
+	if err != nil {
+		return "", err
+	}
+
+	if !uuidFormat.MatchString(val) {
+		return "", &ParamError{Source: "param", Name: name, Value: val, Err: ErrInvalidUUID}
+	}
+
+	return strings.ToLower(val), nil
+}
+
+// ParamTime returns the route parameter parsed as a time using the layout
+func (c *Context) ParamTime(name, layout string) (time.Time, error) {
+	val, err := c.paramString(name)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	tm, err := time.Parse(layout, val)
+	if err != nil {
+		return time.Time{}, &ParamError{Source: "param", Name: name, Value: val, Err: err}
+	}
+
+	return tm, nil
+}
+
+// Query returns the first query value with the name, an empty string is returned if it does not exist
+func (c *Context) Query(name string) string {
+	return c.Req.URL.Query().Get(name)
+}
+
+// QueryInt returns the query value as an int
+func (c *Context) QueryInt(name string) (int, error) {
+	val, err := c.QueryInt64(name)
+	return int(val), err
+}
+
+// QueryInt64 returns the query value as an int64
+func (c *Context) QueryInt64(name string) (int64, error) {
+	val, err := c.queryString(name)
+	if err != nil {
+		return 0, err
+	}
+
+	num, err := strconv.ParseInt(val, 10, 64)
+	if err != nil {
+		return 0, &ParamError{Source: "query", Name: name, Value: val, Err: err}
+	}
+
+	return num, nil
+}
+
+// QueryFloat returns the query value as a float64
+func (c *Context) QueryFloat(name string) (float64, error) {
+	val, err := c.queryString(name)
+	if err != nil {
+		return 0, err
+	}
+
+	num, err := strconv.ParseFloat(val, 64)
+	if err != nil {
+		return 0, &ParamError{Source: "query", Name: name, Value: val, Err: err}
+	}
+
+	return num, nil
+}
+
+// QueryBool returns the query value as a bool
+func (c *Context) QueryBool(name string) (bool, error) {
+	val, err := c.queryString(name)
+	if err != nil {
+		return false, err
+	}
+
+	state, err := strconv.ParseBool(val)
+	if err != nil {
+		return false, &ParamError{Source: "query", Name: name, Value: val, Err: err}
+	}
+
+	return state, nil
+}
+
+// QueryTime returns the query value parsed as a time using the layout
+func (c *Context) QueryTime(name, layout string) (time.Time, error) {
+	val, err := c.queryString(name)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	tm, err := time.Parse(layout, val)
+	if err != nil {
+		return time.Time{}, &ParamError{Source: "query", Name: name, Value: val, Err: err}
+	}
+
+	return tm, nil
+}
+
+// BindParams fills the fields of the struct pointed to by v using their tags, where
+// 'param' reads route parameters, 'query' reads the query string and 'header' reads
+// the request headers. Fields of type time.Time use the 'layout' tag or time.RFC3339.
+// All conversion failures are returned together as ParamErrors
+//
+//   type Search struct {
+//     User  int       `param:"id"`
+//     Tags  []string  `query:"tag"`
+//     Since time.Time `query:"since" layout:"2006-01-02"`
+//     Token string    `header:"X-Token"`
+//   }
+func (c *Context) BindParams(v interface{}) error {
+	return bindValues(v, func(source, name string) ([]string, bool) {
+		switch source {
+		case "param":
+			val, err := c.paramString(name)
+			if err != nil {
+				return nil, false
+			}
+			return []string{val}, true
+		case "query":
+			vals, ok := c.Req.URL.Query()[name]
+			return vals, ok
+		case "header":
+			vals, ok := c.Req.Header[http.CanonicalHeaderKey(name)]
+			return vals, ok
+		}
+		return nil, false
+	}, "param", "query", "header")
+}
+
+// paramString returns the route parameter as a string
+func (c *Context) paramString(name string) (string, error) {
+	if !c.Has(name) {
+		return "", &ParamError{Source: "param", Name: name, Err: ErrParamNotFound}
+	}
+
+	switch val := c.Get(name).(type) {
+	case string:
+		return val, nil
+	case nil:
+		return "", nil
+	default:
+		return fmt.Sprint(val), nil
+	}
+}
+
+// queryString returns the first query value with the name
+func (c *Context) queryString(name string) (string, error) {
+	vals, ok := c.Req.URL.Query()[name]
+	if !ok || len(vals) == 0 {
+		return "", &ParamError{Source: "query", Name: name, Err: ErrParamNotFound}
+	}
+	return vals[0], nil
+}
+
+// valueLookup provides a function type for retrieving the values of a tag source
+type valueLookup func(source, name string) ([]string, bool)
+
+// bindValues fills the tagged fields of the struct pointed to by v using the lookup
+// for each of the tag sources
+func bindValues(v interface{}, lookup valueLookup, sources ...string) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return ErrNotStructPointer
+	}
+
+	var errs ParamErrors
+	bindStruct(rv.Elem(), lookup, sources, &errs)
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+// bindStruct fills the fields of the struct value, descending into embedded structs
+func bindStruct(rv reflect.Value, lookup valueLookup, sources []string, errs *ParamErrors) {
+	rt := rv.Type()
+
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		fv := rv.Field(i)
+
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+
+		if field.Anonymous && fv.Kind() == reflect.Struct {
+			bindStruct(fv, lookup, sources, errs)
+			continue
+		}
+
+		for _, source := range sources {
+			name := field.Tag.Get(source)
+			if name == "" || name == "-" {
+				continue
+			}
+
+			vals, ok := lookup(source, name)
+			if !ok || len(vals) == 0 {
+				continue
+			}
+
+			if err := setFieldValue(fv, vals, field.Tag.Get("layout")); err != nil {
+				*errs = append(*errs, &ParamError{Source: source, Name: name, Value: strings.Join(vals, ","), Err: err})
+			}
+			break
+		}
+	}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// setFieldValue converts the values into the type of the field and sets it
+func setFieldValue(fv reflect.Value, vals []string, layout string) error {
+	if fv.Kind() == reflect.Ptr {
+		if fv.IsNil() {
+			fv.Set(reflect.New(fv.Type().Elem()))
+		}
+		return setFieldValue(fv.Elem(), vals, layout)
+	}
+
+	if fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() != reflect.Uint8 {
+		items := reflect.MakeSlice(fv.Type(), len(vals), len(vals))
+		for i, val := range vals {
+			if err := setFieldValue(items.Index(i), []string{val}, layout); err != nil {
+				return err
+			}
+		}
+		fv.Set(items)
+		return nil
+	}
+
+	val := vals[0]
+
+	if fv.Type() == timeType {
+		if layout == "" {
+			layout = time.RFC3339
+		}
+
+		tm, err := time.Parse(layout, val)
+		if err != nil {
+			return err
+		}
+
+		fv.Set(reflect.ValueOf(tm))
+		return nil
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(val)
+	case reflect.Slice:
+		fv.SetBytes([]byte(val))
+	case reflect.Bool:
+		state, err := strconv.ParseBool(val)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(state)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if fv.Type() == reflect.TypeOf(time.Duration(0)) {
+			dur, err := time.ParseDuration(val)
+			if err != nil {
+				return err
+			}
+			fv.SetInt(int64(dur))
+			return nil
+		}
+
+		num, err := strconv.ParseInt(val, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetInt(num)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		num, err := strconv.ParseUint(val, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetUint(num)
+	case reflect.Float32, reflect.Float64:
+		num, err := strconv.ParseFloat(val, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(num)
+	default:
+		return fmt.Errorf("unsupported field type %s", fv.Type())
+	}
+
+	return nil
+}
@@ -0,0 +1,76 @@
+package relay
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/influx6/flux"
+)
+
+func TestContextParams(t *testing.T) {
+	req, _ := http.NewRequest("GET", "http://localhost:3000/users/20?page=3&since=2015-10-01&tag=a&tag=b", nil)
+	req.Header.Set("X-Token", "secret")
+
+	c := NewContext(httptest.NewRecorder(), req)
+	c.Set("id", "20")
+	c.Set("uid", "6BA7B810-9DAD-11D1-80B4-00C04FD430C8")
+	c.Set("name", "alex")
+
+	id, err := c.ParamInt("id")
+	if err != nil {
+		flux.FatalFailed(t, "Unable to convert param: %s", err)
+	}
+	expect(t, id, 20)
+
+	uid, err := c.ParamUUID("uid")
+	if err != nil {
+		flux.FatalFailed(t, "Unable to convert param: %s", err)
+	}
+	expect(t, uid, "6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+
+	if _, err := c.ParamInt("name"); err == nil {
+		flux.FatalFailed(t, "Expected conversion error for 'name'")
+	}
+
+	if _, err := c.ParamInt("missing"); err == nil {
+		flux.FatalFailed(t, "Expected not found error for 'missing'")
+	}
+
+	page, err := c.QueryInt("page")
+	if err != nil {
+		flux.FatalFailed(t, "Unable to convert query: %s", err)
+	}
+	expect(t, page, 3)
+
+	var search struct {
+		ID    int       `param:"id"`
+		Page  *int      `query:"page"`
+		Tags  []string  `query:"tag"`
+		Since time.Time `query:"since" layout:"2006-01-02"`
+		Token string    `header:"x-token"`
+	}
+
+	if err := c.BindParams(&search); err != nil {
+		flux.FatalFailed(t, "Unable to bind params: %s", err)
+	}
+
+	expect(t, search.ID, 20)
+	expect(t, *search.Page, 3)
+	expect(t, len(search.Tags), 2)
+	expect(t, search.Since.Month(), time.October)
+	expect(t, search.Token, "secret")
+
+	var bad struct {
+		Name  int  `param:"name"`
+		Token bool `header:"X-Token"`
+	}
+
+	err = c.BindParams(&bad)
+	errs, ok := err.(ParamErrors)
+	if !ok {
+		flux.FatalFailed(t, "Expected ParamErrors but got %+v", err)
+	}
+	expect(t, len(errs), 2)
+}
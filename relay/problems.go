@@ -0,0 +1,239 @@
+package relay
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"runtime/debug"
+	"sort"
+)
+
+// problems.go provides rendering of errors as problem details responses (RFC 7807)
+//
+//   app.Group("/api", relay.ProblemFlatHandler(false)).Rule("get", "/users/:id", func(c *relay.Context, next relay.NextHandler) {
+//     panic(relay.NewHTTPError(404, "no user with the given id").With("id", c.Param("id")))
+//   })
+
+const (
+	// ContentProblemJSON header value for json problem details
+	ContentProblemJSON = "application/problem+json"
+	// ContentProblemXML header value for xml problem details
+	ContentProblemXML = "application/problem+xml"
+	// problemNamespace provides the xml namespace of problem details
+	problemNamespace = "urn:ietf:rfc:7807"
+)
+
+// ProblemEncoder provides an encoder which renders a HTTPError as json problem details
+var ProblemEncoder = NewEncoder(func(w io.Writer, d interface{}) (int, error) {
+	he, ok := d.(*HTTPError)
+
+	if !ok {
+		return 0, NewCustomError("ProblemEncoder", "Wrong type,expected HTTPError type")
+	}
+
+	res, err := json.Marshal(problemMembers(he))
+	if err != nil {
+		return 0, err
+	}
+
+	return w.Write(res)
+})
+
+// ProblemXMLEncoder provides an encoder which renders a HTTPError as xml problem details
+var ProblemXMLEncoder = NewEncoder(func(w io.Writer, d interface{}) (int, error) {
+	he, ok := d.(*HTTPError)
+
+	if !ok {
+		return 0, NewCustomError("ProblemXMLEncoder", "Wrong type,expected HTTPError type")
+	}
+
+	res, err := xml.Marshal(xmlProblem{he})
+	if err != nil {
+		return 0, err
+	}
+
+	return w.Write(res)
+})
+
+// Problem renders the error as problem details in json, or xml if preferred by
+// the Accept header, see AsHTTPError for the conversion of errors
+func (c *Context) Problem(err error) error {
+	return c.problem(AsHTTPError(err, false))
+}
+
+// problem renders the HTTPError with the encoder matching the Accept header
+func (c *Context) problem(he *HTTPError) error {
+	status := he.Status
+	if status == 0 {
+		status = http.StatusInternalServerError
+	}
+
+	content, enc := ContentProblemJSON, ProblemEncoder
+
+	ranges := parseAccept(c.Req.Header.Get("Accept"))
+	if len(ranges) > 0 {
+		jq, _ := acceptQuality(ranges, "application/json")
+		pjq, _ := acceptQuality(ranges, ContentProblemJSON)
+		xq, _ := acceptQuality(ranges, "application/xml")
+		pxq, _ := acceptQuality(ranges, ContentProblemXML)
+
+		if maxFloat(xq, pxq) > maxFloat(jq, pjq) {
+			content, enc = ContentProblemXML, ProblemXMLEncoder
+		}
+	}
+
+	return c.Respond(enc, &Head{Status: status, Content: content}, he)
+}
+
+// ProblemHandler returns a function which renders errors as problem details and
+// logs server errors, debug adds the message of unknown errors as the detail
+func ProblemHandler(debug bool) func(*Context, error) {
+	return func(c *Context, err error) {
+		he := AsHTTPError(err, debug)
+
+		if he.Status >= http.StatusInternalServerError {
+			c.Log.Printf("Request %s %s failed: %s", c.Req.Method, c.Req.URL.Path, err)
+		}
+
+		if c.Res.Written() {
+			return
+		}
+
+		if rerr := c.problem(he); rerr != nil {
+			c.Log.Printf("Unable to render problem for %s: %s", c.Req.URL.Path, rerr)
+		}
+	}
+}
+
+// ProblemFlatHandler returns a FlatHandler which recovers panics within the rest
+// of the chain and renders them as problem details, panics with a HTTPError keep
+// its status while others become a 500. Debug adds the panic and its stack trace
+func ProblemFlatHandler(debugMode bool) FlatHandler {
+	handle := ProblemHandler(debugMode)
+
+	return func(c *Context, next NextHandler) {
+		defer func() {
+			rc := recover()
+			if rc == nil {
+				return
+			}
+
+			err, ok := rc.(error)
+			if !ok {
+				err = fmt.Errorf("%v", rc)
+			}
+
+			if debugMode {
+				//copy the error to avoid adding the stack to shared HTTPErrors
+				he := *AsHTTPError(err, true)
+				he.Extra = make(map[string]interface{})
+
+				if orig, ok := err.(*HTTPError); ok {
+					for key, val := range orig.Extra {
+						he.Extra[key] = val
+					}
+				}
+
+				he.Extra["stack"] = string(debug.Stack())
+				err = &he
+			}
+
+			handle(c, err)
+		}()
+
+		next(c)
+	}
+}
+
+// problemMembers returns the members of the problem with its extra members, the
+// standard members can not be replaced by the extra ones
+func problemMembers(he *HTTPError) map[string]interface{} {
+	members := make(map[string]interface{}, len(he.Extra)+5)
+
+	for key, val := range he.Extra {
+		members[key] = val
+	}
+
+	problemType := he.Type
+	if problemType == "" {
+		problemType = "about:blank"
+	}
+
+	members["type"] = problemType
+	members["title"] = he.Title
+	members["status"] = he.Status
+
+	if he.Detail != "" {
+		members["detail"] = he.Detail
+	}
+
+	if he.Instance != "" {
+		members["instance"] = he.Instance
+	}
+
+	return members
+}
+
+// xmlProblem provides the xml encoding of a HTTPError
+type xmlProblem struct {
+	*HTTPError
+}
+
+// MarshalXML encodes the problem members as elements of a problem element
+func (x xmlProblem) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	start = xml.StartElement{Name: xml.Name{Local: "problem"}, Attr: []xml.Attr{{Name: xml.Name{Local: "xmlns"}, Value: problemNamespace}}}
+
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+
+	members := problemMembers(x.HTTPError)
+
+	//standard members first followed by the extra members in order
+	keys := []string{"type", "title", "status", "detail", "instance"}
+	var extra []string
+	for key := range x.Extra {
+		if _, ok := members[key]; ok && !isProblemMember(key) {
+			extra = append(extra, key)
+		}
+	}
+	sort.Strings(extra)
+
+	for _, key := range append(keys, extra...) {
+		val, ok := members[key]
+		if !ok {
+			continue
+		}
+
+		//maps have no xml encoding and are written in their printed form
+		if rv := reflect.Indirect(reflect.ValueOf(val)); rv.Kind() == reflect.Map {
+			val = fmt.Sprint(rv.Interface())
+		}
+
+		if err := e.EncodeElement(val, xml.StartElement{Name: xml.Name{Local: key}}); err != nil {
+			return err
+		}
+	}
+
+	return e.EncodeToken(start.End())
+}
+
+// isProblemMember returns true/false if the key is one of the standard problem members
+func isProblemMember(key string) bool {
+	switch key {
+	case "type", "title", "status", "detail", "instance":
+		return true
+	}
+	return false
+}
+
+// maxFloat returns the larger of the values
+func maxFloat(a, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,108 @@
+package relay
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/influx6/flux"
+)
+
+func TestProblemResponses(t *testing.T) {
+	req, _ := http.NewRequest("GET", "http://localhost:3000/users/20", nil)
+
+	rec := httptest.NewRecorder()
+	c := NewContext(rec, req)
+
+	he := NewHTTPError(http.StatusNotFound, "no user with id 20").With("id", 20)
+	he.Type = "https://example.com/problems/missing-user"
+
+	if err := c.Problem(he); err != nil {
+		flux.FatalFailed(t, "Unable to render problem: %s", err)
+	}
+
+	expect(t, rec.Code, http.StatusNotFound)
+	expect(t, rec.Header().Get("Content-Type"), ContentProblemJSON)
+
+	var body map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		flux.FatalFailed(t, "Unable to decode problem: %s", err)
+	}
+
+	expect(t, body["type"], "https://example.com/problems/missing-user")
+	expect(t, body["title"], "Not Found")
+	expect(t, body["status"], float64(404))
+	expect(t, body["detail"], "no user with id 20")
+	expect(t, body["id"], float64(20))
+
+	//xml is used when preferred by the client
+	req.Header.Set("Accept", "application/xml, application/json;q=0.5")
+
+	rec = httptest.NewRecorder()
+	c = NewContext(rec, req)
+
+	if err := c.Problem(ValidationErrors{{Field: "name", Rule: "required", Message: "is required"}}); err != nil {
+		flux.FatalFailed(t, "Unable to render problem: %s", err)
+	}
+
+	expect(t, rec.Code, http.StatusUnprocessableEntity)
+	expect(t, rec.Header().Get("Content-Type"), ContentProblemXML)
+
+	if !strings.HasPrefix(rec.Body.String(), `<problem xmlns="urn:ietf:rfc:7807"><type>about:blank</type><title>Unprocessable Entity</title><status>422</status>`) {
+		flux.FatalFailed(t, "Unexpected xml problem: %s", rec.Body.String())
+	}
+
+	if !strings.Contains(rec.Body.String(), "<errors><field>name</field><rule>required</rule><message>is required</message></errors>") {
+		flux.FatalFailed(t, "Expected validation errors in xml problem: %s", rec.Body.String())
+	}
+
+	//unknown errors hide their message unless in debug mode
+	expect(t, AsHTTPError(errors.New("db down"), false).Detail, "")
+	expect(t, AsHTTPError(errors.New("db down"), true).Detail, "db down")
+	expect(t, AsHTTPError(ErrBodyTooLarge, false).Status, http.StatusRequestEntityTooLarge)
+}
+
+func TestProblemFlatHandler(t *testing.T) {
+	missing := NewHTTPError(http.StatusNotFound, "missing")
+
+	router := NewChainRouter(nil, nil)
+	api := router.Group("/api", ProblemFlatHandler(true))
+
+	api.Rule("get", "/missing", func(c *Context, next NextHandler) {
+		panic(missing)
+	})
+
+	api.Rule("get", "/crash", func(c *Context, next NextHandler) {
+		panic("crashed")
+	})
+
+	req, _ := http.NewRequest("GET", "http://localhost:3000/api/missing", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	expect(t, rec.Code, http.StatusNotFound)
+
+	var body map[string]interface{}
+	json.Unmarshal(rec.Body.Bytes(), &body)
+
+	if _, ok := body["stack"]; !ok {
+		flux.FatalFailed(t, "Expected a stack trace in debug mode: %s", rec.Body.String())
+	}
+
+	if missing.Extra != nil {
+		flux.FatalFailed(t, "Expected shared HTTPError to be left unchanged")
+	}
+
+	req, _ = http.NewRequest("GET", "http://localhost:3000/api/crash", nil)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	body = nil
+	json.Unmarshal(rec.Body.Bytes(), &body)
+
+	expect(t, rec.Code, http.StatusInternalServerError)
+	expect(t, body["detail"], "crashed")
+}
@@ -43,10 +43,12 @@ type Message struct {
 	MessageType string
 	Method      string
 	Payload     []byte
-	Form        url.Values
-	PostForm    url.Values
-	Multipart   *multipart.Form
-	Params      Collector
+	//Body is set instead of Payload by the StreamDecoder, it fails with ErrBodyTooLarge once the body limit is exceeded
+	Body      io.Reader
+	Form      url.Values
+	PostForm  url.Values
+	Multipart *multipart.Form
+	Params    Collector
 }
 
 //MessageDecoder provides the message decoding decoder for *Context objects
@@ -54,6 +56,11 @@ var MessageDecoder = NewHTTPDecoder(func(req *Context) (*Message, error) {
 	return loadData(req)
 })
 
+//StreamDecoder provides a message decoder which does not buffer request bodies, exposing them through Message.Body with the 'stream' message type
+var StreamDecoder = NewHTTPDecoder(func(req *Context) (*Message, error) {
+	return loadStream(req)
+})
+
 // UseHTTPEncoder wires up the MessageDecoder as an automatic decoder
 func UseHTTPEncoder(enc Encoder) HTTPCodec {
 	return NewHTTPCodec(enc, MessageDecoder)
@@ -97,9 +104,9 @@ func WriteHead(c *Context, h *Head) {
 	WriteRawHead(c.Res, h)
 }
 
-//WriteRawHead writes a head struct into a ResponseWriter
+//WriteRawHead writes a head struct into a ResponseWriter, the headers and
+//Content-Type are set before the status is written as they are ignored afterwards
 func WriteRawHead(c http.ResponseWriter, h *Head) {
-	c.WriteHeader(h.Status)
 	//copy over the headers
 	for k, v := range h.Headers {
 		for _, vs := range v {
@@ -109,10 +116,12 @@ func WriteRawHead(c http.ResponseWriter, h *Head) {
 
 	//write the Content-Type if not a empty string, we do it down here to preserve
 	//the given value incase there was an over-write in the headers provided
-	//TODO: decide wether to use .Add() or .Set()
-
 	if h.Content != "" {
-		c.Header().Add("Content-Type", h.Content)
+		c.Header().Set("Content-Type", h.Content)
+	}
+
+	if h.Status > 0 {
+		c.WriteHeader(h.Status)
 	}
 }
 
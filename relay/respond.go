@@ -0,0 +1,78 @@
+package relay
+
+import (
+	"html/template"
+	"io"
+	"strconv"
+)
+
+// respond.go provides rendering helpers on the Context which encode the response
+// body before writing the head, so encoding errors are returned before any bytes
+// are sent and the Content-Length can be set
+
+// JSON renders the data as json with the status
+func (c *Context) JSON(status int, data interface{}) error {
+	jso := JSONRender(status, data, false, false, false)
+	return c.Respond(JSONEncoder, jso.Head, jso)
+}
+
+// XML renders the data as xml with the status
+func (c *Context) XML(status int, data interface{}) error {
+	xo := XMLRender(status, false, data, nil)
+	return c.Respond(XMLEncoder, xo.Head, xo)
+}
+
+// HTML renders the named template with the binding and status
+func (c *Context) HTML(status int, layout string, binding interface{}, tl *template.Template) error {
+	ho := HTMLRender(status, layout, binding, tl)
+	return c.Respond(HTMLEncoder, ho.Head, ho)
+}
+
+// Text renders the text with the status
+func (c *Context) Text(status int, text string) error {
+	to := TextRender(status, text)
+	return c.Respond(TextEncoder, to.Head, to)
+}
+
+// JSONP renders the data as json wrapped in the callback with the status
+func (c *Context) JSONP(status int, callback string, data interface{}) error {
+	jop := JSONPRender(status, false, callback, data)
+	return c.Respond(JSONPEncoder, jop.Head, jop)
+}
+
+// Blob writes the bytes with the content type and status
+func (c *Context) Blob(status int, content string, data []byte) error {
+	return c.Respond(ByteEncoder, &Head{Status: status, Content: content}, data)
+}
+
+// Stream writes the head and copies the reader into the response without
+// buffering, flushing once done. No Content-Length is set
+func (c *Context) Stream(status int, content string, r io.Reader) error {
+	if err := BasicHeadEncoder.Encode(c, &Head{Status: status, Content: content}); err != nil {
+		return err
+	}
+
+	_, err := io.Copy(c.Res, r)
+	c.Res.Flush()
+	return err
+}
+
+// Respond encodes the value with the encoder into a buffer and only writes the
+// head, with the buffered Content-Length, and body if the encoding succeeded
+func (c *Context) Respond(enc Encoder, h *Head, value interface{}) error {
+	buf := bufPool.Get()
+	defer bufPool.Put(buf)
+
+	if _, err := enc.Encode(buf, value); err != nil {
+		return err
+	}
+
+	c.Res.Header().Set(ContentLength, strconv.Itoa(buf.Len()))
+
+	if err := BasicHeadEncoder.Encode(c, h); err != nil {
+		return err
+	}
+
+	_, err := buf.WriteTo(c.Res)
+	return err
+}
@@ -0,0 +1,55 @@
+package relay
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/influx6/flux"
+)
+
+func TestContextRender(t *testing.T) {
+	req, _ := http.NewRequest("GET", "http://localhost:3000/users", nil)
+
+	rec := httptest.NewRecorder()
+	c := NewContext(rec, req)
+
+	if err := c.JSON(http.StatusCreated, map[string]string{"user": "alex"}); err != nil {
+		flux.FatalFailed(t, "Unable to render json: %s", err)
+	}
+
+	res := rec.Result()
+	expect(t, res.StatusCode, http.StatusCreated)
+	expect(t, res.Header.Get("Content-Type"), ContentJSON)
+	expect(t, res.Header.Get("Content-Length"), "15")
+	expect(t, rec.Body.String(), `{"user":"alex"}`)
+
+	rec = httptest.NewRecorder()
+	c = NewContext(rec, req)
+
+	if err := c.JSON(http.StatusOK, func() {}); err == nil {
+		flux.FatalFailed(t, "Expected json encoding error")
+	}
+	expect(t, c.Res.Written(), false)
+	expect(t, rec.Body.Len(), 0)
+
+	rec = httptest.NewRecorder()
+	c = NewContext(rec, req)
+
+	if err := c.Stream(http.StatusOK, ContentBinary, bytes.NewBufferString("stream")); err != nil {
+		flux.FatalFailed(t, "Unable to stream: %s", err)
+	}
+	expect(t, rec.Result().Header.Get("Content-Type"), ContentBinary)
+	expect(t, rec.Body.String(), "stream")
+
+	rec = httptest.NewRecorder()
+	c = NewContext(rec, req)
+
+	headers := make(http.Header)
+	headers.Set("X-Relay", "1")
+
+	BasicHeadEncoder.Encode(c, &Head{Status: http.StatusAccepted, Content: ContentText, Headers: headers})
+	expect(t, rec.Result().Header.Get("X-Relay"), "1")
+	expect(t, rec.Result().Header.Get("Content-Type"), ContentText)
+}
@@ -3,6 +3,7 @@ package relay
 import (
 	"bufio"
 	"errors"
+	"io"
 	"net"
 	"net/http"
 )
@@ -10,23 +11,32 @@ import (
 // modes.go contains specific modification structs or decorators over standard
 //go interfaces for useful bits
 
-// ResponseWriter provides a clean interface decorated over the http.ResponseWriter
+// ResponseWriter provides a clean interface decorated over the http.ResponseWriter,
+// the optional interfaces of the underlying writer are passed through when supported
 type ResponseWriter interface {
 	http.ResponseWriter
 	http.Flusher
 	http.Hijacker
-	// http.CloseNotifier
+	http.Pusher
+	io.ReaderFrom
 	Status() int
 	Size() int
 	Written() bool
 	WritePayload(int, []byte) error
+	//Done returns a channel closed once the request is done or the client disconnects
+	Done() <-chan struct{}
+	//Before adds a function run just before the status and headers are written
+	Before(func(ResponseWriter))
 }
 
 // responseWriter provides the concrete implementation of ResponseWriter
 type responseWriter struct {
-	w      http.ResponseWriter
-	status int
-	size   int
+	w          http.ResponseWriter
+	done       <-chan struct{}
+	status     int
+	size       int
+	before     []func(ResponseWriter)
+	committing bool
 }
 
 // NewResponseWriter returns a new responseWriter
@@ -35,6 +45,12 @@ func NewResponseWriter(w http.ResponseWriter) ResponseWriter {
 	return &rw
 }
 
+// NewRequestResponseWriter returns a new responseWriter whose Done channel is the request context's
+func NewRequestResponseWriter(w http.ResponseWriter, req *http.Request) ResponseWriter {
+	rw := responseWriter{w: w, done: req.Context().Done()}
+	return &rw
+}
+
 // ErrNotHijackable is returned when a response writer can not be hijacked
 var ErrNotHijackable = errors.New("ResponseWriter cant be Hijacked")
 
@@ -46,17 +62,58 @@ func (rw *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
 	return nil, nil, ErrNotHijackable
 }
 
+// Push initiates a HTTP/2 server push if supported by the internal http.ResponseWriter
+func (rw *responseWriter) Push(target string, opts *http.PushOptions) error {
+	if pw, ok := rw.w.(http.Pusher); ok {
+		return pw.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+// ReadFrom copies the reader into the response, using the internal http.ResponseWriter's
+// io.ReaderFrom when supported to allow the server to use sendfile
+func (rw *responseWriter) ReadFrom(r io.Reader) (int64, error) {
+	if !rw.Written() {
+		rw.WriteHeader(http.StatusOK)
+	}
+
+	var n int64
+	var err error
+
+	if rf, ok := rw.w.(io.ReaderFrom); ok {
+		n, err = rf.ReadFrom(r)
+	} else {
+		n, err = io.Copy(rw.w, r)
+	}
+
+	rw.size += int(n)
+	return n, err
+}
+
 // WritePayload writes a payload ignoring the type
 func (rw *responseWriter) WritePayload(c int, p []byte) error {
-	_, err := rw.w.Write(p)
+	_, err := rw.Write(p)
 	return err
 }
 
+// Before adds a function run just before the status and headers are written,
+// the functions are run in the reverse order they were added
+func (rw *responseWriter) Before(fx func(ResponseWriter)) {
+	rw.before = append(rw.before, fx)
+}
+
 // WriteHeader writes the status code for the http response
 func (rw *responseWriter) WriteHeader(c int) {
-	if rw.Written() {
+	if rw.Written() || rw.committing {
 		return
 	}
+
+	rw.committing = true
+	for i := len(rw.before) - 1; i >= 0; i-- {
+		rw.before[i](rw)
+	}
+	rw.committing = false
+
 	rw.status = c
 	rw.w.WriteHeader(c)
 }
@@ -69,7 +126,7 @@ func (rw *responseWriter) Header() http.Header {
 // Write writes the supplied data into the internal resposne writer
 func (rw *responseWriter) Write(b []byte) (int, error) {
 	if !rw.Written() {
-		rw.status = http.StatusOK
+		rw.WriteHeader(http.StatusOK)
 	}
 
 	n, err := rw.w.Write(b)
@@ -89,9 +146,10 @@ func (rw *responseWriter) Flush() {
 	}
 }
 
-// CloseNotify returns a receive-only channel
-func (rw *responseWriter) CloseNotify() <-chan bool {
-	return rw.w.(http.CloseNotifier).CloseNotify()
+// Done returns a channel closed once the request is done, it is nil and never
+// closes for writers created without a request
+func (rw *responseWriter) Done() <-chan struct{} {
+	return rw.done
 }
 
 // Written returns true/false if the status code has been written
@@ -103,3 +161,9 @@ func (rw *responseWriter) Written() bool {
 func (rw *responseWriter) Size() int {
 	return rw.size
 }
+
+// writerOnly hides the io.ReaderFrom of a writer, used by wrapping writers to
+// copy readers through their own Write
+type writerOnly struct {
+	io.Writer
+}
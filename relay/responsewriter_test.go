@@ -0,0 +1,71 @@
+package relay
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/influx6/flux"
+)
+
+// readerFromRecorder provides a ResponseRecorder which records its use as an io.ReaderFrom
+type readerFromRecorder struct {
+	*httptest.ResponseRecorder
+	readFrom bool
+}
+
+func (r *readerFromRecorder) ReadFrom(rd io.Reader) (int64, error) {
+	r.readFrom = true
+	return io.Copy(r.ResponseRecorder, rd)
+}
+
+func TestResponseWriter(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequest("GET", "http://localhost:3000/", nil)
+	req = req.WithContext(ctx)
+
+	rec := &readerFromRecorder{ResponseRecorder: httptest.NewRecorder()}
+	rw := NewRequestResponseWriter(rec, req)
+
+	var order []string
+	rw.Before(func(w ResponseWriter) {
+		order = append(order, "first")
+	})
+	rw.Before(func(w ResponseWriter) {
+		order = append(order, "second")
+		w.Header().Set("X-Hook", "true")
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	if err := rw.WritePayload(0, []byte("hello ")); err != nil {
+		flux.FatalFailed(t, "Unable to write payload: %s", err)
+	}
+
+	if _, err := io.Copy(rw, io.LimitReader(strings.NewReader("world"), 5)); err != nil {
+		flux.FatalFailed(t, "Unable to copy into response: %s", err)
+	}
+
+	expect(t, strings.Join(order, ","), "second,first")
+	expect(t, rec.Code, http.StatusOK)
+	expect(t, rec.Header().Get("X-Hook"), "true")
+	expect(t, rec.Body.String(), "hello world")
+	expect(t, rw.Size(), 11)
+	expect(t, rw.Status(), http.StatusOK)
+	expect(t, rec.readFrom, true)
+
+	if err := rw.Push("/app.css", nil); err != http.ErrNotSupported {
+		flux.FatalFailed(t, "Expected http.ErrNotSupported: %s", err)
+	}
+
+	select {
+	case <-rw.Done():
+		flux.FatalFailed(t, "Expected Done to be open")
+	default:
+	}
+
+	cancel()
+	<-rw.Done()
+}
@@ -1,10 +1,13 @@
 package relay
 
 import (
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
+
+	"github.com/influx6/reggy"
 )
 
 func BenchmarkRouter(t *testing.B) {
@@ -127,3 +130,168 @@ func TestRouter(t *testing.T) {
 	router.ServeHTTP(rec, req6)
 	router.ServeHTTP(rec, req7)
 }
+
+func TestRouterTree(t *testing.T) {
+	router := NewChainRouter(nil, nil)
+
+	var hits []string
+
+	router.Rule("get", "/boo/bat", func(c *Context, next NextHandler) {
+		hits = append(hits, "static")
+	})
+
+	router.Rule("get", "/boo/:id", func(c *Context, next NextHandler) {
+		hits = append(hits, "param")
+		expect(t, c.Get("id"), "bat")
+	})
+
+	router.Rule("get", `/header/{id:[\d]+}`, func(c *Context, next NextHandler) {
+		hits = append(hits, "regex")
+		expect(t, c.Get("id"), "40")
+	})
+
+	router.Rule("get", "/assets/*", func(c *Context, next NextHandler) {
+		hits = append(hits, "wildcard")
+		expect(t, c.Get("*"), "css/main.css")
+	})
+
+	router.Rule("get", "/users/:user/posts/:post", func(c *Context, next NextHandler) {
+		hits = append(hits, "nested")
+		expect(t, c.Get("user"), "alex")
+		expect(t, c.Get("post"), "12")
+	})
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		hits = nil
+		req, _ := http.NewRequest("GET", "http://localhost:3000"+path, nil)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	serve("/boo/bat")
+	expect(t, len(hits), 2)
+	expect(t, hits[0], "static")
+	expect(t, hits[1], "param")
+
+	serve("/header/40")
+	expect(t, len(hits), 1)
+	expect(t, hits[0], "regex")
+
+	serve("/assets/css/main.css")
+	expect(t, len(hits), 1)
+	expect(t, hits[0], "wildcard")
+
+	serve("/users/alex/posts/12/")
+	expect(t, len(hits), 1)
+	expect(t, hits[0], "nested")
+
+	rec := serve("/header/boo")
+	expect(t, len(hits), 0)
+	expect(t, rec.Code, http.StatusNotFound)
+}
+
+// benchRouter returns a ChainRouter with a few hundred registered rules
+func benchRouter() *ChainRouter {
+	router := NewChainRouter(nil, nil)
+
+	for i := 0; i < 100; i++ {
+		router.Rule("get", fmt.Sprintf("/api/v1/resource%d", i), nil)
+		router.Rule("get post", fmt.Sprintf("/api/v1/resource%d/:id", i), nil)
+		router.Rule("get", fmt.Sprintf(`/api/v1/resource%d/:id/items/{item:[\d]+}`, i), nil)
+	}
+
+	return router
+}
+
+func BenchmarkRouterStatic(t *testing.B) {
+	router := benchRouter()
+	req, _ := http.NewRequest("GET", "http://localhost:3000/api/v1/resource99", nil)
+	rec := httptest.NewRecorder()
+
+	t.ReportAllocs()
+	t.ResetTimer()
+	for i := 0; i < t.N; i++ {
+		router.ServeHTTP(rec, req)
+	}
+}
+
+func BenchmarkRouterParams(t *testing.B) {
+	router := benchRouter()
+	req, _ := http.NewRequest("GET", "http://localhost:3000/api/v1/resource99/30/items/20", nil)
+	rec := httptest.NewRecorder()
+
+	t.ReportAllocs()
+	t.ResetTimer()
+	for i := 0; i < t.N; i++ {
+		router.ServeHTTP(rec, req)
+	}
+}
+
+func BenchmarkRouterLinearScan(t *testing.B) {
+	var paths []*reggy.ClassicMatchMux
+
+	for i := 0; i < 100; i++ {
+		paths = append(paths, reggy.CreateClassic(fmt.Sprintf("/api/v1/resource%d", i)))
+		paths = append(paths, reggy.CreateClassic(fmt.Sprintf("/api/v1/resource%d/:id", i)))
+		paths = append(paths, reggy.CreateClassic(fmt.Sprintf(`/api/v1/resource%d/:id/items/{item:[\d]+}`, i)))
+	}
+
+	t.ReportAllocs()
+	t.ResetTimer()
+	for i := 0; i < t.N; i++ {
+		for _, ra := range paths {
+			ra.Validate("/api/v1/resource99/30/items/20")
+		}
+	}
+}
+
+func TestExclusiveRouter(t *testing.T) {
+	router := NewChainRouter(nil, nil)
+	router.Exclusive = true
+
+	var hits []string
+
+	router.Rule("get", "/boo/:id", func(c *Context, next NextHandler) {
+		hits = append(hits, "get")
+		c.Res.Write([]byte("boo"))
+	})
+
+	router.Rule("get put", "/boo/:id", func(c *Context, next NextHandler) {
+		hits = append(hits, "put")
+	})
+
+	router.Rule("delete", "/boo/:name", func(c *Context, next NextHandler) {
+		hits = append(hits, "delete")
+	})
+
+	serve := func(method string) *httptest.ResponseRecorder {
+		hits = nil
+		req, _ := http.NewRequest(method, "http://localhost:3000/boo/bat", nil)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	serve("GET")
+	expect(t, len(hits), 1)
+	expect(t, hits[0], "get")
+
+	serve("PUT")
+	expect(t, len(hits), 1)
+	expect(t, hits[0], "put")
+
+	rec := serve("HEAD")
+	expect(t, len(hits), 1)
+	expect(t, hits[0], "get")
+
+	rec = serve("POST")
+	expect(t, len(hits), 0)
+	expect(t, rec.Code, http.StatusMethodNotAllowed)
+	expect(t, rec.Header().Get("Allow"), "GET, HEAD, PUT, DELETE, OPTIONS")
+
+	rec = serve("OPTIONS")
+	expect(t, len(hits), 0)
+	expect(t, rec.Code, http.StatusOK)
+	expect(t, rec.Header().Get("Allow"), "GET, HEAD, PUT, DELETE, OPTIONS")
+}
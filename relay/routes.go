@@ -1,8 +1,10 @@
 package relay
 
 import (
+	"fmt"
 	"log"
 	"net/http"
+	"strings"
 	"sync"
 
 	"github.com/influx6/reggy"
@@ -32,15 +34,34 @@ type PanicHandler func(http.ResponseWriter, *http.Request, interface{})
 type ChainRouta struct {
 	*reggy.ClassicMatchMux
 	Handler RHandler
+	Methods []string
+	Name    string
+	host    *hostPattern
+	handler interface{}
+	serve   RHandler
+	pattern string
+	tokens  []routeToken
+	index   int
 }
 
 // ChainRouter provides an alternative routing strategy of registered ChainRoutas using the FlatChains, its process is when any stack matches, its passed the requests to that handler and continues on, but if non matching is found,it executes a failure routine i.e it matches as many as possible unless non matches
 type ChainRouter struct {
 	FlatChains
 	paths []*ChainRouta
+	names map[string]*ChainRouta
+	hosts []*hostMount
+	tree  *routeTree
 	wg    sync.RWMutex
 	Fail  RHandler
 	Log   *log.Logger
+	//Exclusive switches the router into first-match mode, where only the first rule matching both the path and method is run, a path matching with the wrong method gets a 405 and OPTIONS and HEAD requests are answered from the registered methods
+	Exclusive bool
+	//NotAllowed is called in Exclusive mode when the path matched but not the method, the 'Allow' header is already set when its called
+	NotAllowed RHandler
+	//Templates provides the templates used by Context.Render within the router's routes and mounted routers
+	Templates *Templates
+	//ErrorHandler handles the errors returned by ErrFlatHandlers within the router's routes and mounted routers, DefaultErrorHandler is used when nil
+	ErrorHandler ErrorHandler
 }
 
 // NewChainRouter returns a new ChainRouter instance
@@ -48,6 +69,8 @@ func NewChainRouter(fail RHandler, lg *log.Logger) *ChainRouter {
 	sa := ChainRouter{
 		FlatChains: FlatChainIdentity(lg),
 		paths:      make([]*ChainRouta, 0),
+		names:      make(map[string]*ChainRouta),
+		tree:       newRouteTree(),
 		Fail:       fail,
 		Log:        lg,
 	}
@@ -56,58 +79,204 @@ func NewChainRouter(fail RHandler, lg *log.Logger) *ChainRouter {
 
 // BareRule defines a matching rule for a specified pattern
 func (r *ChainRouter) BareRule(mo, pattern string, fx RHandler) {
+	r.NamedBareRule("", mo, pattern, fx)
+}
+
+// NamedBareRule defines a matching rule for a specified pattern which can be referred to by its name when building urls
+func (r *ChainRouter) NamedBareRule(name, mo, pattern string, fx RHandler) {
 	methods := GetMethods(mo)
 	patt := reggy.CreateClassic(pattern)
-	cr := &ChainRouta{ClassicMatchMux: patt, Handler: BuildMatchesMethod(methods, fx)}
-	r.wg.Lock()
-	defer r.wg.Unlock()
-	r.paths = append(r.paths, cr)
+	cr := &ChainRouta{
+		ClassicMatchMux: patt,
+		Handler:         BuildMatchesMethod(methods, fx),
+		Methods:         methods,
+		Name:            name,
+		handler:         fx,
+		serve:           fx,
+	}
+	r.add(pattern, cr)
 }
 
 // Rule defines a matching rule which returns a flatchain
 func (r *ChainRouter) Rule(mo, pattern string, fx FlatHandler) FlatChains {
+	return r.NamedRule("", mo, pattern, fx)
+}
+
+// NamedRule defines a matching rule which returns a flatchain and can be referred to by its name when building urls
+func (r *ChainRouter) NamedRule(name, mo, pattern string, fx FlatHandler) FlatChains {
 	if fx == nil {
 		fx = IdentityCall
 	}
+	fr := NewFlatChain(fx, r.Log)
+	r.chainRule(name, mo, pattern, nil, fx, fr)
+	return fr
+}
+
+// chainRule registers a FlatChains as the handler of a matching rule, restricted to the host pattern if not nil, fx is the handler reported by Routes
+func (r *ChainRouter) chainRule(name, mo, pattern string, host *hostPattern, fx interface{}, fr FlatChains) {
 	methods := GetMethods(mo)
 	patt := reggy.CreateClassic(pattern)
-	fr := FlatRouteBuild(methods, patt, fx, r.Log)
-	cr := &ChainRouta{ClassicMatchMux: patt, Handler: fr.Handle}
+	cr := &ChainRouta{
+		ClassicMatchMux: patt,
+		Handler:         BuildMatchesMethod(methods, fr.Handle),
+		Methods:         methods,
+		Name:            name,
+		host:            host,
+		handler:         fx,
+		serve:           fr.Handle,
+	}
+	r.add(pattern, cr)
+}
+
+// add registers the ChainRouta into the routers path list and route tree
+func (r *ChainRouter) add(pattern string, cr *ChainRouta) {
+	tokens, err := parseRoutePattern(pattern)
+	if err != nil {
+		panic(err)
+	}
+
 	r.wg.Lock()
 	defer r.wg.Unlock()
+
+	if cr.Name != "" {
+		if _, ok := r.names[cr.Name]; ok {
+			panic(NewCustomError("ChainRouter", fmt.Sprintf("route name %q is already registered", cr.Name)))
+		}
+		r.names[cr.Name] = cr
+	}
+
+	cr.pattern = pattern
+	cr.tokens = tokens
+	cr.index = len(r.paths)
+
+	r.tree.insert(tokens, cr)
 	r.paths = append(r.paths, cr)
-	return fr
 }
 
 // ServeHTTP provides the handling of http requests and meets the http.Handler interface
 func (r *ChainRouter) ServeHTTP(w http.ResponseWriter, rq *http.Request) {
+	r.serve(w, rq, nil)
+}
+
+// serve dispatches the request to the mounted host routers or the matching
+// routes, adding the supplied parameters to those captured by the route
+func (r *ChainRouter) serve(w http.ResponseWriter, rq *http.Request, params Collector) {
 	r.FlatChains.ServeHTTP(w, rq)
-	//check if any of our regexp matches
-	var found bool
-	var c map[string]interface{}
-
-	for _, ra := range r.paths {
-		ok, params := ra.ClassicMatchMux.Validate(rq.URL.Path)
-		c = params
-		if ok {
-			found = true
-			ra.Handler(w, rq, Collector(params))
+
+	if r.Templates != nil {
+		rq = withTemplates(rq, r.Templates)
+	}
+
+	if r.ErrorHandler != nil {
+		rq = withErrorHandler(rq, r.ErrorHandler)
+	}
+
+	host := requestHost(rq)
+
+	r.wg.RLock()
+	mounts := r.hosts
+	r.wg.RUnlock()
+
+	for _, mo := range mounts {
+		if ok, hp := mo.host.match(host); ok {
+			mo.router.serve(w, rq, mergeParams(params, hp))
+			return
 		}
 	}
 
-	if !found {
+	//collect all routes matching the path in the order they were registered
+	r.wg.RLock()
+	matches := r.tree.Match(rq.URL.Path)
+	r.wg.RUnlock()
+
+	matches = filterHosts(matches, host, params)
+
+	if len(matches) == 0 {
 		if r.Fail == nil {
 			http.NotFound(w, rq)
 		} else {
-			r.Fail(w, rq, c)
+			r.Fail(w, rq, params)
+		}
+		return
+	}
+
+	if r.Exclusive {
+		r.serveFirst(w, rq, matches)
+		return
+	}
+
+	for _, ra := range matches {
+		ra.route.Handler(w, rq, ra.params)
+	}
+}
+
+// serveFirst runs the first matched route allowing the request method, else
+// answers OPTIONS and HEAD requests or replies with a 405
+func (r *ChainRouter) serveFirst(w http.ResponseWriter, rq *http.Request, matches []routeMatch) {
+	method := strings.ToLower(rq.Method)
+
+	for _, ra := range matches {
+		if len(ra.route.Methods) == 0 || HasMethod(ra.route.Methods, method) {
+			ra.route.serve(w, rq, ra.params)
+			return
+		}
+	}
+
+	//HEAD requests are served by the GET routes, the server drops the body
+	if method == "head" {
+		for _, ra := range matches {
+			if HasMethod(ra.route.Methods, "get") {
+				ra.route.serve(w, rq, ra.params)
+				return
+			}
+		}
+	}
+
+	w.Header().Set("Allow", allowedMethods(matches))
+
+	if method == "options" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.NotAllowed != nil {
+		r.NotAllowed(w, rq, matches[0].params)
+		return
+	}
+
+	http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+}
+
+// allowedMethods returns the value of the 'Allow' header for the matched routes
+func allowedMethods(matches []routeMatch) string {
+	var allowed []string
+	seen := make(map[string]bool)
+
+	add := func(m string) {
+		m = strings.ToUpper(m)
+		if !seen[m] {
+			seen[m] = true
+			allowed = append(allowed, m)
+		}
+	}
+
+	for _, ra := range matches {
+		for _, m := range ra.route.Methods {
+			add(m)
+			if strings.ToLower(m) == "get" {
+				add("head")
+			}
 		}
 	}
 
+	add("options")
+	return strings.Join(allowed, ", ")
 }
 
-// Handle meets the FlatHandler interface for serving http requests
-func (r *ChainRouter) Handle(w http.ResponseWriter, rq *http.Request, _ Collector) {
-	r.ServeHTTP(w, rq)
+// Handle meets the FlatHandler interface for serving http requests, the parameters
+// given are added to those captured by the matching routes
+func (r *ChainRouter) Handle(w http.ResponseWriter, rq *http.Request, co Collector) {
+	r.serve(w, rq, co)
 }
 
 // Redirect redirects all incoming request to the path
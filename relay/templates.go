@@ -0,0 +1,323 @@
+package relay
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// templates.go provides a registry of html templates loaded from a directory,
+// supporting layout inheritance, shared partials and reloading on changes.
+//
+// Templates are named by their path relative to the directory without the
+// extension, i.e 'users/index'. Files within the 'partials' directory are
+// available to every template. A template extends a layout by starting with an
+// extends comment and overriding the layout's blocks, layouts can extend others:
+//
+//   {{/* extends "layouts/base" */}}
+//   {{define "content"}}<h1>{{.Title}}</h1>{{template "partials/nav" .}}{{end}}
+
+var extendsRule = regexp.MustCompile(`^\s*\{\{/\*\s*extends\s+"([^"]+)"\s*\*/\}\}`)
+
+// templatesKey provides the request context key used by the ChainRouter to
+// make its Templates available to Context.Render
+type templatesKey struct{}
+
+// Templates provides a registry of html templates loaded from a directory
+type Templates struct {
+	Dir string
+	Ext string
+	//Reload makes the templates re-parse when files in the directory change, meant for development
+	Reload bool
+	//Funcs are added to every template before parsing, DefaultFuncs are set by NewTemplates
+	Funcs template.FuncMap
+
+	rw    sync.RWMutex
+	views map[string]*templateView
+	stamp string
+}
+
+// templateView provides a parsed template and the name of its root template
+type templateView struct {
+	root string
+	tpl  *template.Template
+}
+
+// NewTemplates returns a new Templates registry for the directory and extension,
+// reload sets the registry to re-parse on changes instead of caching
+func NewTemplates(dir, ext string, reload bool) *Templates {
+	return &Templates{
+		Dir:    dir,
+		Ext:    ext,
+		Reload: reload,
+		Funcs:  DefaultFuncs(),
+	}
+}
+
+// AddFuncs adds the functions to the templates, replacing those with the same
+// name, templates already loaded are re-parsed on their next use
+func (t *Templates) AddFuncs(funcs template.FuncMap) *Templates {
+	t.rw.Lock()
+	defer t.rw.Unlock()
+
+	if t.Funcs == nil {
+		t.Funcs = make(template.FuncMap)
+	}
+
+	for name, fn := range funcs {
+		t.Funcs[name] = fn
+	}
+
+	t.views = nil
+	return t
+}
+
+// Load parses all the templates within the directory
+func (t *Templates) Load() error {
+	stamp, err := t.dirStamp()
+	if err != nil {
+		return err
+	}
+
+	views, err := t.parse()
+	if err != nil {
+		return err
+	}
+
+	t.rw.Lock()
+	t.views = views
+	t.stamp = stamp
+	t.rw.Unlock()
+
+	return nil
+}
+
+// Lookup returns the template with the name and the name of the template to execute,
+// the templates are loaded on first use and re-parsed on changes if Reload is set
+func (t *Templates) Lookup(name string) (*template.Template, string, error) {
+	t.rw.RLock()
+	views, stamp := t.views, t.stamp
+	t.rw.RUnlock()
+
+	if views == nil {
+		if err := t.Load(); err != nil {
+			return nil, "", err
+		}
+	} else if t.Reload {
+		current, err := t.dirStamp()
+		if err != nil {
+			return nil, "", err
+		}
+
+		if current != stamp {
+			if err := t.Load(); err != nil {
+				return nil, "", err
+			}
+		}
+	}
+
+	t.rw.RLock()
+	view, ok := t.views[name]
+	t.rw.RUnlock()
+
+	if !ok {
+		return nil, "", NewCustomError("Templates", fmt.Sprintf("template %q not found in %s", name, t.Dir))
+	}
+
+	return view.tpl, view.root, nil
+}
+
+// Execute renders the template with the name and data into the writer
+func (t *Templates) Execute(w io.Writer, name string, data interface{}) error {
+	tpl, root, err := t.Lookup(name)
+	if err != nil {
+		return err
+	}
+	return tpl.ExecuteTemplate(w, root, data)
+}
+
+// HTML returns a HTML struct for rendering the named template with the HTMLEncoder
+func (t *Templates) HTML(status int, name string, data interface{}) (*HTML, error) {
+	tpl, root, err := t.Lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	return HTMLRender(status, root, data, tpl), nil
+}
+
+// parse reads the template files and builds a template set for each of them
+func (t *Templates) parse() (map[string]*templateView, error) {
+	sources := make(map[string]string)
+
+	err := filepath.Walk(t.Dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() || !strings.HasSuffix(path, t.Ext) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(t.Dir, path)
+		if err != nil {
+			return err
+		}
+
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		sources[filepath.ToSlash(strings.TrimSuffix(rel, t.Ext))] = string(data)
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	//copy the functions as AddFuncs may change them during a reload
+	t.rw.RLock()
+	funcs := make(template.FuncMap, len(t.Funcs))
+	for name, fn := range t.Funcs {
+		funcs[name] = fn
+	}
+	t.rw.RUnlock()
+
+	base := template.New("").Funcs(funcs)
+
+	for name, src := range sources {
+		if !strings.HasPrefix(name, "partials/") {
+			continue
+		}
+
+		if _, err := base.New(name).Parse(src); err != nil {
+			return nil, err
+		}
+	}
+
+	views := make(map[string]*templateView)
+
+	for name := range sources {
+		if strings.HasPrefix(name, "partials/") {
+			continue
+		}
+
+		chain, err := extendsChain(sources, name)
+		if err != nil {
+			return nil, err
+		}
+
+		tpl, err := base.Clone()
+		if err != nil {
+			return nil, err
+		}
+
+		//parse from the root layout down so each child overrides its parent's blocks
+		for _, link := range chain {
+			if _, err := tpl.New(link).Parse(sources[link]); err != nil {
+				return nil, err
+			}
+		}
+
+		views[name] = &templateView{root: chain[0], tpl: tpl}
+	}
+
+	return views, nil
+}
+
+// extendsChain returns the list of layouts the template extends starting from the root layout
+func extendsChain(sources map[string]string, name string) ([]string, error) {
+	chain := []string{name}
+	seen := map[string]bool{name: true}
+
+	for current := name; ; {
+		found := extendsRule.FindStringSubmatch(sources[current])
+		if found == nil {
+			return chain, nil
+		}
+
+		parent := found[1]
+
+		if _, ok := sources[parent]; !ok {
+			return nil, NewCustomError("Templates", fmt.Sprintf("template %q extends unknown template %q", current, parent))
+		}
+
+		if seen[parent] {
+			return nil, NewCustomError("Templates", fmt.Sprintf("template %q has a cyclic extends of %q", current, parent))
+		}
+
+		seen[parent] = true
+		chain = append([]string{parent}, chain...)
+		current = parent
+	}
+}
+
+// dirStamp returns a value which changes when template files are added, removed or modified
+func (t *Templates) dirStamp() (string, error) {
+	var count int
+	var latest time.Time
+
+	err := filepath.Walk(t.Dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() || !strings.HasSuffix(path, t.Ext) {
+			return nil
+		}
+
+		count++
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+		return nil
+	})
+
+	return fmt.Sprintf("%d:%d", count, latest.UnixNano()), err
+}
+
+// UseTemplates returns a FlatHandler which makes the templates available to Context.Render
+func UseTemplates(t *Templates) FlatHandler {
+	return func(c *Context, next NextHandler) {
+		c.Templates = t
+		next(c)
+	}
+}
+
+// withTemplates returns the request carrying the templates for Context.Render
+func withTemplates(rq *http.Request, t *Templates) *http.Request {
+	return rq.WithContext(context.WithValue(rq.Context(), templatesKey{}, t))
+}
+
+// ErrNoTemplates is returned by Context.Render when no Templates are available to the Context
+var ErrNoTemplates = NewCustomError("Context.Render", "no Templates set for the Context or its router")
+
+// Render renders the named template from the Templates of the Context, or those of
+// the ChainRouter serving the request, with the data and status
+func (c *Context) Render(status int, name string, data interface{}) error {
+	t := c.Templates
+	if t == nil {
+		t, _ = c.Req.Context().Value(templatesKey{}).(*Templates)
+	}
+
+	if t == nil {
+		return ErrNoTemplates
+	}
+
+	ho, err := t.HTML(status, name, data)
+	if err != nil {
+		return err
+	}
+
+	return c.Respond(HTMLEncoder, ho.Head, ho)
+}
@@ -0,0 +1,157 @@
+package relay
+
+import (
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influx6/flux"
+)
+
+func writeTemplate(t *testing.T, dir, name, content string) {
+	path := filepath.Join(dir, name)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		flux.FatalFailed(t, "Unable to create template dir: %s", err)
+	}
+
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		flux.FatalFailed(t, "Unable to write template: %s", err)
+	}
+}
+
+func TestTemplates(t *testing.T) {
+	dir, err := ioutil.TempDir("", "relay-templates")
+	if err != nil {
+		flux.FatalFailed(t, "Unable to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeTemplate(t, dir, "layouts/base.tmpl", `<html>{{block "content" .}}base{{end}}</html>`)
+	writeTemplate(t, dir, "layouts/admin.tmpl", `{{/* extends "layouts/base" */}}{{define "content"}}<nav>{{block "main" .}}{{end}}</nav>{{end}}`)
+	writeTemplate(t, dir, "partials/name.tmpl", `<b>{{.}}</b>`)
+	writeTemplate(t, dir, "users/index.tmpl", `{{/* extends "layouts/base" */}}{{define "content"}}{{template "partials/name" .Name}}{{end}}`)
+	writeTemplate(t, dir, "admin/index.tmpl", `{{/* extends "layouts/admin" */}}{{define "main"}}admin {{.Name}}{{end}}`)
+
+	tm := NewTemplates(dir, ".tmpl", true)
+
+	router := NewChainRouter(nil, nil)
+	router.Templates = tm
+
+	router.Rule("get", "/users", func(c *Context, next NextHandler) {
+		if err := c.Render(http.StatusOK, "users/index", map[string]string{"Name": "alex"}); err != nil {
+			flux.FatalFailed(t, "Unable to render template: %s", err)
+		}
+		next(c)
+	})
+
+	router.Rule("get", "/admin", func(c *Context, next NextHandler) {
+		if err := c.Render(http.StatusAccepted, "admin/index", map[string]string{"Name": "bob"}); err != nil {
+			flux.FatalFailed(t, "Unable to render template: %s", err)
+		}
+		next(c)
+	})
+
+	req, _ := http.NewRequest("GET", "http://localhost:3000/users", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	expect(t, rec.Code, http.StatusOK)
+	expect(t, rec.Header().Get("Content-Type"), ContentHTML)
+	expect(t, rec.Body.String(), "<html><b>alex</b></html>")
+
+	req, _ = http.NewRequest("GET", "http://localhost:3000/admin", nil)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	expect(t, rec.Code, http.StatusAccepted)
+	expect(t, rec.Body.String(), "<html><nav>admin bob</nav></html>")
+
+	//changes are picked up when reloading
+	writeTemplate(t, dir, "partials/name.tmpl", `<i>{{.}}</i>`)
+	future := time.Now().Add(time.Minute)
+	os.Chtimes(filepath.Join(dir, "partials/name.tmpl"), future, future)
+
+	req, _ = http.NewRequest("GET", "http://localhost:3000/users", nil)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	expect(t, rec.Body.String(), "<html><i>alex</i></html>")
+
+	if _, _, err := tm.Lookup("partials/name"); err == nil {
+		flux.FatalFailed(t, "Expected partials not to be rendered directly")
+	}
+
+	//cached templates ignore changes
+	cached := NewTemplates(dir, ".tmpl", false)
+	if err := cached.Load(); err != nil {
+		flux.FatalFailed(t, "Unable to load templates: %s", err)
+	}
+
+	writeTemplate(t, dir, "partials/name.tmpl", `<u>{{.}}</u>`)
+	later := future.Add(time.Minute)
+	os.Chtimes(filepath.Join(dir, "partials/name.tmpl"), later, later)
+
+	rec = httptest.NewRecorder()
+	if err := cached.Execute(rec, "users/index", map[string]string{"Name": "alex"}); err != nil {
+		flux.FatalFailed(t, "Unable to execute template: %s", err)
+	}
+	expect(t, rec.Body.String(), "<html><i>alex</i></html>")
+
+	c := NewContext(httptest.NewRecorder(), req)
+	if err := c.Render(http.StatusOK, "users/index", nil); err != ErrNoTemplates {
+		flux.FatalFailed(t, "Expected ErrNoTemplates: %s", err)
+	}
+}
+
+func TestTemplatesCyclicExtends(t *testing.T) {
+	dir, err := ioutil.TempDir("", "relay-templates")
+	if err != nil {
+		flux.FatalFailed(t, "Unable to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeTemplate(t, dir, "a.tmpl", `{{/* extends "b" */}}`)
+	writeTemplate(t, dir, "b.tmpl", `{{/* extends "a" */}}`)
+
+	if err := NewTemplates(dir, ".tmpl", false).Load(); err == nil {
+		flux.FatalFailed(t, "Expected cyclic extends error")
+	}
+}
+
+func TestTemplatesAddFuncsWhileLoading(t *testing.T) {
+	dir, err := ioutil.TempDir("", "relay-templates")
+	if err != nil {
+		flux.FatalFailed(t, "Unable to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeTemplate(t, dir, "index.tmpl", `{{upper .}}`)
+
+	tm := NewTemplates(dir, ".tmpl", true)
+	tm.AddFuncs(template.FuncMap{"upper": strings.ToUpper})
+
+	//functions added during a reload must not race with the parsing
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 200; i++ {
+			tm.AddFuncs(template.FuncMap{fmt.Sprintf("fn%d", i): strings.ToLower})
+		}
+	}()
+
+	for i := 0; i < 200; i++ {
+		if err := tm.Load(); err != nil {
+			flux.FatalFailed(t, "Unable to load templates: %s", err)
+		}
+	}
+
+	<-done
+}
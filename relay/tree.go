@@ -0,0 +1,366 @@
+package relay
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// tree.go provides a compressed prefix tree used by the ChainRouter to resolve
+// request paths without scanning every registered rule
+
+// routeKind defines the type of a routeNode within the routeTree
+type routeKind int
+
+const (
+	staticKind routeKind = iota
+	paramKind
+	regexKind
+	catchAllKind
+)
+
+// routeToken provides a single parsed element of a route pattern
+type routeToken struct {
+	kind  routeKind
+	text  string
+	name  string
+	rx    *regexp.Regexp
+	slash bool
+}
+
+// routeNode provides a single node in the routeTree, static nodes consume their
+// prefix while the others consume a path segment or the rest of the path
+type routeNode struct {
+	kind    routeKind
+	prefix  string
+	name    string
+	rx      *regexp.Regexp
+	slash   bool
+	indices []byte
+	statics []*routeNode
+	wilds   []*routeNode
+	routes  []*ChainRouta
+}
+
+// routeMatch provides a matched route and the parameters captured for it
+type routeMatch struct {
+	route  *ChainRouta
+	params Collector
+}
+
+// routeMatches provides a sortable list of routeMatch using their registration order
+type routeMatches []routeMatch
+
+func (r routeMatches) Len() int           { return len(r) }
+func (r routeMatches) Less(i, j int) bool { return r[i].route.index < r[j].route.index }
+func (r routeMatches) Swap(i, j int)      { r[i], r[j] = r[j], r[i] }
+
+// routeTree provides a compressed prefix tree of routes supporting static
+// segments, ':param', '{param:regex}' and '*' wildcards
+type routeTree struct {
+	root *routeNode
+}
+
+// newRouteTree returns a new routeTree instance
+func newRouteTree() *routeTree {
+	return &routeTree{root: &routeNode{kind: staticKind}}
+}
+
+// insert adds the route into the tree using an already parsed pattern
+func (t *routeTree) insert(tokens []routeToken, cr *ChainRouta) {
+	t.root.insert(tokens, cr)
+}
+
+// Match returns all routes matching the supplied path in their registration order
+func (t *routeTree) Match(path string) []routeMatch {
+	var matches routeMatches
+	var params []string
+
+	t.root.match(cleanRoutePath(path), params, &matches)
+
+	sort.Sort(matches)
+	return matches
+}
+
+// insert adds the tokens as a branch under the node, terminating with the route
+func (n *routeNode) insert(tokens []routeToken, cr *ChainRouta) {
+	if len(tokens) == 0 {
+		n.routes = append(n.routes, cr)
+		return
+	}
+
+	tk := tokens[0]
+
+	if tk.kind == staticKind {
+		n.insertStatic(tk.text, tokens[1:], cr)
+		return
+	}
+
+	for _, wild := range n.wilds {
+		if wild.sameAs(tk) {
+			wild.insert(tokens[1:], cr)
+			return
+		}
+	}
+
+	wild := &routeNode{
+		kind:  tk.kind,
+		name:  tk.name,
+		rx:    tk.rx,
+		slash: tk.slash,
+	}
+
+	n.wilds = append(n.wilds, wild)
+	wild.insert(tokens[1:], cr)
+}
+
+// insertStatic adds the static text as a child of the node, splitting any
+// existing child that shares a common prefix with it
+func (n *routeNode) insertStatic(text string, rest []routeToken, cr *ChainRouta) {
+	for i, c := range n.indices {
+		if c != text[0] {
+			continue
+		}
+
+		child := n.statics[i]
+		common := commonPrefix(child.prefix, text)
+
+		if common < len(child.prefix) {
+			split := &routeNode{
+				kind:    staticKind,
+				prefix:  child.prefix[:common],
+				indices: []byte{child.prefix[common]},
+				statics: []*routeNode{child},
+			}
+
+			child.prefix = child.prefix[common:]
+			n.statics[i] = split
+			child = split
+		}
+
+		if common == len(text) {
+			child.insert(rest, cr)
+			return
+		}
+
+		child.insertStatic(text[common:], rest, cr)
+		return
+	}
+
+	child := &routeNode{kind: staticKind, prefix: text}
+	n.indices = append(n.indices, text[0])
+	n.statics = append(n.statics, child)
+	child.insert(rest, cr)
+}
+
+// sameAs returns true/false if the node matches the same way as the token
+func (n *routeNode) sameAs(tk routeToken) bool {
+	if n.kind != tk.kind || n.name != tk.name || n.slash != tk.slash {
+		return false
+	}
+
+	if n.rx == nil || tk.rx == nil {
+		return n.rx == tk.rx
+	}
+
+	return n.rx.String() == tk.rx.String()
+}
+
+// match walks the node's children using the remaining path, collecting every
+// route which completely consumes the path
+func (n *routeNode) match(path string, params []string, matches *routeMatches) {
+	if path == "" {
+		n.collect(params, matches)
+	} else {
+		for i, c := range n.indices {
+			if c != path[0] {
+				continue
+			}
+
+			child := n.statics[i]
+			if strings.HasPrefix(path, child.prefix) {
+				child.match(path[len(child.prefix):], params, matches)
+			}
+			break
+		}
+	}
+
+	for _, wild := range n.wilds {
+		switch wild.kind {
+		case catchAllKind:
+			if wild.slash && path != "" && path[0] != '/' {
+				continue
+			}
+
+			wild.collect(append(params, wild.name, strings.TrimPrefix(path, "/")), matches)
+		default:
+			end := strings.IndexByte(path, '/')
+			if end == -1 {
+				end = len(path)
+			}
+
+			segment := path[:end]
+
+			if segment == "" {
+				continue
+			}
+
+			if wild.rx != nil && !wild.rx.MatchString(segment) {
+				continue
+			}
+
+			wild.match(path[end:], append(params, wild.name, segment), matches)
+		}
+	}
+}
+
+// collect adds the routes terminating at this node into the matches
+func (n *routeNode) collect(params []string, matches *routeMatches) {
+	for _, cr := range n.routes {
+		co := make(Collector, len(params)/2)
+		for i := 0; i < len(params); i += 2 {
+			co.Set(params[i], params[i+1])
+		}
+		*matches = append(*matches, routeMatch{route: cr, params: co})
+	}
+}
+
+// parseRoutePattern turns a route pattern into a list of tokens for the routeTree
+func parseRoutePattern(pattern string) ([]routeToken, error) {
+	var tokens []routeToken
+	var static []byte
+
+	pattern = cleanRoutePath(pattern)
+
+	flush := func() {
+		if len(static) > 0 {
+			tokens = append(tokens, routeToken{kind: staticKind, text: string(static)})
+			static = nil
+		}
+	}
+
+	for i := 0; i < len(pattern); i++ {
+		ch := pattern[i]
+		atSegment := i == 0 || pattern[i-1] == '/'
+
+		switch {
+		case ch == ':' && atSegment:
+			end := segmentEnd(pattern, i)
+			name := pattern[i+1 : end]
+
+			if name == "" {
+				return nil, fmt.Errorf("route pattern %q has an unnamed parameter", pattern)
+			}
+
+			flush()
+			tokens = append(tokens, routeToken{kind: paramKind, name: name})
+			i = end - 1
+
+		case ch == '{' && atSegment:
+			end := braceEnd(pattern, i)
+
+			if end == -1 {
+				return nil, fmt.Errorf("route pattern %q has an unclosed '{'", pattern)
+			}
+
+			body := pattern[i+1 : end]
+			flush()
+
+			if ix := strings.Index(body, ":"); ix != -1 {
+				rx, err := regexp.Compile("^(?:" + body[ix+1:] + ")$")
+				if err != nil {
+					return nil, err
+				}
+				tokens = append(tokens, routeToken{kind: regexKind, name: body[:ix], rx: rx})
+			} else {
+				tokens = append(tokens, routeToken{kind: paramKind, name: body})
+			}
+
+			i = end
+
+		case ch == '*':
+			if end := segmentEnd(pattern, i); end != len(pattern) {
+				return nil, fmt.Errorf("route pattern %q can only have a '*' as its last segment", pattern)
+			}
+
+			name := pattern[i+1:]
+			if name == "" {
+				name = "*"
+			}
+
+			//a '/*' suffix also matches the path without the trailing slash,
+			//except at the root where the '/' is the whole static part
+			var slash bool
+			if n := len(static); n > 0 && static[n-1] == '/' && (n > 1 || len(tokens) > 0) {
+				static = static[:n-1]
+				slash = true
+			}
+
+			flush()
+			tokens = append(tokens, routeToken{kind: catchAllKind, name: name, slash: slash})
+			return tokens, nil
+
+		default:
+			static = append(static, ch)
+		}
+	}
+
+	flush()
+	return tokens, nil
+}
+
+// cleanRoutePath ensures a path starts with a '/' and has no trailing '/'
+func cleanRoutePath(path string) string {
+	if path == "" || path[0] != '/' {
+		path = "/" + path
+	}
+
+	if len(path) > 1 && path[len(path)-1] == '/' {
+		path = strings.TrimRight(path, "/")
+		if path == "" {
+			path = "/"
+		}
+	}
+
+	return path
+}
+
+// segmentEnd returns the index of the next '/' after the start index or the length of the path
+func segmentEnd(path string, start int) int {
+	if end := strings.IndexByte(path[start:], '/'); end != -1 {
+		return start + end
+	}
+	return len(path)
+}
+
+// braceEnd returns the index of the '}' closing the '{' at the start index
+func braceEnd(path string, start int) int {
+	var depth int
+	for i := start; i < len(path); i++ {
+		switch path[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// commonPrefix returns the length of the shared prefix of both strings
+func commonPrefix(a, b string) int {
+	max := len(a)
+	if len(b) < max {
+		max = len(b)
+	}
+
+	var i int
+	for i < max && a[i] == b[i] {
+		i++
+	}
+	return i
+}
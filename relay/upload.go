@@ -0,0 +1,490 @@
+package relay
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// upload.go provides streaming of multipart uploads into an UploadStore, checking
+// the size and type of each file and computing its checksum while it is stored
+//
+//   store := relay.NewDiskStore("./uploads")
+//
+//   app.Rule("post", "/avatars", relay.ErrFlat(func(c *relay.Context, next relay.NextHandler) error {
+//     up, err := c.Upload(relay.UploadConfig{
+//       Store:        store,
+//       MaxFileSize:  2 << 20,
+//       AllowedTypes: []string{"image/png", "image/jpeg"},
+//     })
+//     if err != nil {
+//       return err
+//     }
+//     return c.JSON(201, up.Files)
+//   }))
+
+// ErrNotMultipart is returned when an upload request is not a multipart/form-data request
+var ErrNotMultipart = errors.New("request is not multipart/form-data")
+
+// ErrFileTooLarge is returned when an uploaded file exceeds UploadConfig.MaxFileSize
+var ErrFileTooLarge = errors.New("uploaded file too large")
+
+// ErrFileTypeNotAllowed is returned when an uploaded file is not of the UploadConfig.AllowedTypes
+var ErrFileTypeNotAllowed = errors.New("uploaded file type not allowed")
+
+// ErrTooManyFiles is returned when a request uploads more than UploadConfig.MaxFiles files
+var ErrTooManyFiles = errors.New("too many uploaded files")
+
+// ErrNoUploadStore is returned when uploading without an UploadConfig.Store
+var ErrNoUploadStore = errors.New("no UploadStore set for uploads")
+
+// ErrUploadNotFound is returned by UploadStores for unknown keys
+var ErrUploadNotFound = errors.New("upload not found")
+
+// ErrUploadIncomplete is returned when an UploadStore saved a file without reading all its content
+var ErrUploadIncomplete = errors.New("upload store did not read the whole file")
+
+// DefaultMaxFieldsSize provides the maximum total size of the non-file fields of an upload
+var DefaultMaxFieldsSize int64 = 1 << 20
+
+// UploadStore provides the storage of uploaded files, Save must read the content
+// until it ends and store nothing when reading fails
+type UploadStore interface {
+	//Save stores the content of the file, returning the key it can be opened with
+	Save(file *UploadedFile, content io.Reader) (string, error)
+	//Open returns the content stored under the key
+	Open(key string) (io.ReadCloser, error)
+	//Delete removes the content stored under the key
+	Delete(key string) error
+}
+
+// UploadConfig provides the limits and store of an upload
+type UploadConfig struct {
+	//Store sets the UploadStore the files are saved into
+	Store UploadStore
+	//MaxSize sets the maximum size of the request body, the Context's body limit is used when zero
+	MaxSize int64
+	//MaxFileSize sets the maximum size of each file, files are only limited by MaxSize when zero
+	MaxFileSize int64
+	//MaxFiles sets the maximum number of files, there is no limit when zero
+	MaxFiles int
+	//MaxFieldsSize sets the maximum total size of the non-file fields, DefaultMaxFieldsSize is used when zero
+	MaxFieldsSize int64
+	//AllowedTypes sets the media types, or their prefixes when ending with a '/', of the files
+	//detected from their content, all types are allowed when empty
+	AllowedTypes []string
+}
+
+// UploadedFile provides the details of a stored file
+type UploadedFile struct {
+	Field       string `json:"field"`
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	SHA256      string `json:"sha256"`
+	Key         string `json:"key"`
+}
+
+// Uploads provides the stored files and the non-file fields of an upload
+type Uploads struct {
+	Files  []*UploadedFile
+	Values url.Values
+}
+
+// Upload streams the files of the multipart/form-data request into the config's
+// UploadStore, detecting their type from their content and computing their checksum
+// as they are stored. When a limit is exceeded or a file is refused the files
+// already stored are deleted and ErrBodyTooLarge, ErrFileTooLarge, ErrTooManyFiles
+// or ErrFileTypeNotAllowed is returned
+func (c *Context) Upload(config UploadConfig) (*Uploads, error) {
+	if config.Store == nil {
+		return nil, ErrNoUploadStore
+	}
+
+	media, params, err := mime.ParseMediaType(c.Req.Header.Get(ContentType))
+	if err != nil || media != "multipart/form-data" || params["boundary"] == "" {
+		return nil, ErrNotMultipart
+	}
+
+	limit := config.MaxSize
+	if limit == 0 {
+		limit = c.bodyLimit()
+	}
+
+	if limit > 0 && c.Req.ContentLength > limit {
+		return nil, ErrBodyTooLarge
+	}
+
+	fieldsLimit := config.MaxFieldsSize
+	if fieldsLimit <= 0 {
+		fieldsLimit = DefaultMaxFieldsSize
+	}
+
+	body := newLimitedBody(c.Req.Body, limit)
+	reader := multipart.NewReader(body, params["boundary"])
+
+	up := &Uploads{Values: make(url.Values)}
+
+	err = func() error {
+		for {
+			part, err := reader.NextPart()
+			if err == io.EOF {
+				return nil
+			}
+
+			if err != nil {
+				if exceeded(body) {
+					return ErrBodyTooLarge
+				}
+				return err
+			}
+
+			if part.FileName() == "" {
+				err = readField(part, up.Values, &fieldsLimit)
+			} else if config.MaxFiles > 0 && len(up.Files) >= config.MaxFiles {
+				err = ErrTooManyFiles
+			} else {
+				var file *UploadedFile
+				if file, err = saveUpload(config, part); err == nil {
+					up.Files = append(up.Files, file)
+				}
+			}
+
+			part.Close()
+
+			if err != nil {
+				if exceeded(body) {
+					return ErrBodyTooLarge
+				}
+				return err
+			}
+		}
+	}()
+
+	if err != nil {
+		for _, file := range up.Files {
+			config.Store.Delete(file.Key)
+		}
+		return nil, err
+	}
+
+	return up, nil
+}
+
+// readField adds the value of the part to the values, reducing the remaining size
+func readField(part *multipart.Part, values url.Values, remaining *int64) error {
+	data, err := ioutil.ReadAll(io.LimitReader(part, *remaining+1))
+	if err != nil {
+		return err
+	}
+
+	if int64(len(data)) > *remaining {
+		return ErrBodyTooLarge
+	}
+
+	*remaining -= int64(len(data))
+	values.Add(part.FormName(), string(data))
+	return nil
+}
+
+// saveUpload checks the type of the part and saves it into the store
+func saveUpload(config UploadConfig, part *multipart.Part) (*UploadedFile, error) {
+	file := &UploadedFile{
+		Field:    part.FormName(),
+		Filename: UploadFilename(part.FileName()),
+	}
+
+	buf := bufio.NewReaderSize(part, 512)
+
+	//a short or empty file is sniffed from what it has
+	sniff, err := buf.Peek(512)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, err
+	}
+
+	file.ContentType = detectUploadType(file.Filename, sniff)
+
+	if !allowedType(config.AllowedTypes, file.ContentType) {
+		return nil, ErrFileTypeNotAllowed
+	}
+
+	ur := &uploadReader{r: buf, limit: config.MaxFileSize, sum: sha256.New()}
+
+	key, err := config.Store.Save(file, ur)
+	if err != nil {
+		if ur.err != nil {
+			return nil, ur.err
+		}
+		return nil, err
+	}
+
+	//stores must read all the content for the size and checksum to be known
+	if !ur.done {
+		config.Store.Delete(key)
+		return nil, ErrUploadIncomplete
+	}
+
+	file.Key = key
+	file.Size = ur.size
+	file.SHA256 = hex.EncodeToString(ur.sum.Sum(nil))
+
+	return file, nil
+}
+
+// UploadFilename returns the base name of the client supplied file name, without
+// any directories or leading dots
+func UploadFilename(name string) string {
+	name = path.Base(strings.Replace(name, "\\", "/", -1))
+	name = strings.TrimLeft(name, ".")
+
+	if name == "" || name == "/" {
+		return "file"
+	}
+
+	return name
+}
+
+// detectUploadType returns the media type sniffed from the content, using the
+// extension of the file name only to refine content sniffed as plain text into
+// a textual type. Binary content which can not be identified stays 'application/octet-stream'
+func detectUploadType(name string, content []byte) string {
+	media := http.DetectContentType(content)
+
+	if !strings.HasPrefix(media, "text/plain") {
+		return media
+	}
+
+	if byExt, ok := Mimes.Lookup(name); ok {
+		base := strings.Split(byExt, ";")[0]
+		if strings.HasPrefix(base, "text/") || textualTypes[base] {
+			return byExt
+		}
+	}
+
+	return media
+}
+
+// allowedType returns true/false if the media type is within the allowed types or their prefixes
+func allowedType(allowed []string, media string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+
+	media = strings.ToLower(strings.TrimSpace(strings.Split(media, ";")[0]))
+
+	for _, al := range allowed {
+		al = strings.ToLower(al)
+		if media == al || (strings.HasSuffix(al, "/") && strings.HasPrefix(media, al)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// uploadReader provides a io.Reader which computes the size and checksum of a file,
+// failing with ErrFileTooLarge once it exceeds the limit
+type uploadReader struct {
+	r     io.Reader
+	limit int64
+	size  int64
+	sum   hash.Hash
+	done  bool
+	err   error
+}
+
+// Read reads the file while updating its size and checksum
+func (u *uploadReader) Read(p []byte) (int, error) {
+	if u.err != nil {
+		return 0, u.err
+	}
+
+	n, err := u.r.Read(p)
+	u.size += int64(n)
+	u.sum.Write(p[:n])
+
+	if u.limit > 0 && u.size > u.limit {
+		u.err = ErrFileTooLarge
+		return 0, u.err
+	}
+
+	if err == io.EOF {
+		u.done = true
+	} else if err != nil {
+		u.err = err
+	}
+
+	return n, err
+}
+
+// uploadKey returns a random key for the file keeping its extension
+func uploadKey(file *UploadedFile) (string, error) {
+	var id [16]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id[:]) + strings.ToLower(path.Ext(file.Filename)), nil
+}
+
+// DiskStore provides an UploadStore saving files into a directory under random keys
+type DiskStore struct {
+	Dir string
+}
+
+// NewDiskStore returns a new DiskStore for the directory, which is created on the first Save
+func NewDiskStore(dir string) *DiskStore {
+	return &DiskStore{Dir: dir}
+}
+
+// Save writes the content into a temporary file which is moved to its key once complete
+func (d *DiskStore) Save(file *UploadedFile, content io.Reader) (string, error) {
+	if err := os.MkdirAll(d.Dir, 0755); err != nil {
+		return "", err
+	}
+
+	key, err := uploadKey(file)
+	if err != nil {
+		return "", err
+	}
+
+	tmp, err := ioutil.TempFile(d.Dir, ".upload-")
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := io.Copy(tmp, content); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return "", err
+	}
+
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+
+	if err := os.Rename(tmp.Name(), filepath.Join(d.Dir, key)); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+
+	return key, nil
+}
+
+// Open returns the file stored under the key
+func (d *DiskStore) Open(key string) (io.ReadCloser, error) {
+	file, err := d.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	fl, err := os.Open(file)
+	if os.IsNotExist(err) {
+		return nil, ErrUploadNotFound
+	}
+
+	return fl, err
+}
+
+// Delete removes the file stored under the key
+func (d *DiskStore) Delete(key string) error {
+	file, err := d.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(file); err != nil {
+		if os.IsNotExist(err) {
+			return ErrUploadNotFound
+		}
+		return err
+	}
+
+	return nil
+}
+
+// path returns the path of the key within the directory, refusing keys naming other paths
+func (d *DiskStore) path(key string) (string, error) {
+	if key == "" || key != filepath.Base(key) || strings.HasPrefix(key, ".") {
+		return "", ErrUploadNotFound
+	}
+	return filepath.Join(d.Dir, key), nil
+}
+
+// MemoryStore provides an UploadStore keeping files in memory, suited for tests
+type MemoryStore struct {
+	rw    sync.RWMutex
+	files map[string][]byte
+}
+
+// NewMemoryStore returns a new MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{files: make(map[string][]byte)}
+}
+
+// Save reads the content into memory, keeping it once complete
+func (m *MemoryStore) Save(file *UploadedFile, content io.Reader) (string, error) {
+	data, err := ioutil.ReadAll(content)
+	if err != nil {
+		return "", err
+	}
+
+	key, err := uploadKey(file)
+	if err != nil {
+		return "", err
+	}
+
+	m.rw.Lock()
+	m.files[key] = data
+	m.rw.Unlock()
+
+	return key, nil
+}
+
+// Open returns the content stored under the key
+func (m *MemoryStore) Open(key string) (io.ReadCloser, error) {
+	m.rw.RLock()
+	data, ok := m.files[key]
+	m.rw.RUnlock()
+
+	if !ok {
+		return nil, ErrUploadNotFound
+	}
+
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
+
+// Delete removes the content stored under the key
+func (m *MemoryStore) Delete(key string) error {
+	m.rw.Lock()
+	defer m.rw.Unlock()
+
+	if _, ok := m.files[key]; !ok {
+		return ErrUploadNotFound
+	}
+
+	delete(m.files, key)
+	return nil
+}
+
+// Len returns the number of files stored
+func (m *MemoryStore) Len() int {
+	m.rw.RLock()
+	defer m.rw.RUnlock()
+	return len(m.files)
+}
@@ -0,0 +1,174 @@
+package relay
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/influx6/flux"
+)
+
+var pngHeader = "\x89PNG\x0D\x0A\x1A\x0A"
+
+// uploadRequest returns a multipart request with the files keyed by their file names and the fields
+func uploadRequest(t *testing.T, files map[string]string, fields map[string]string) *http.Request {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+
+	for name, value := range fields {
+		mw.WriteField(name, value)
+	}
+
+	for name, content := range files {
+		fw, err := mw.CreateFormFile("files", name)
+		if err != nil {
+			flux.FatalFailed(t, "Unable to create form file: %s", err)
+		}
+		fw.Write([]byte(content))
+	}
+
+	mw.Close()
+
+	req, _ := http.NewRequest("POST", "http://localhost:3000/upload", &body)
+	req.Header.Set(ContentType, mw.FormDataContentType())
+	return req
+}
+
+func uploadContext(req *http.Request) *Context {
+	return NewContextWith(httptest.NewRecorder(), req, nil)
+}
+
+func TestUpload(t *testing.T) {
+	store := NewMemoryStore()
+	content := pngHeader + "image"
+
+	req := uploadRequest(t, map[string]string{"../../avatar.png": content}, map[string]string{"title": "me"})
+
+	up, err := uploadContext(req).Upload(UploadConfig{
+		Store:        store,
+		MaxFileSize:  1024,
+		AllowedTypes: []string{"image/"},
+	})
+
+	if err != nil {
+		flux.FatalFailed(t, "Unable to upload: %s", err)
+	}
+
+	sum := sha256.Sum256([]byte(content))
+
+	expect(t, len(up.Files), 1)
+	expect(t, up.Values.Get("title"), "me")
+
+	file := up.Files[0]
+	expect(t, file.Field, "files")
+	expect(t, file.Filename, "avatar.png")
+	expect(t, file.ContentType, "image/png")
+	expect(t, file.Size, int64(len(content)))
+	expect(t, file.SHA256, hex.EncodeToString(sum[:]))
+	expect(t, strings.HasSuffix(file.Key, ".png"), true)
+
+	rc, err := store.Open(file.Key)
+	if err != nil {
+		flux.FatalFailed(t, "Unable to open upload: %s", err)
+	}
+
+	data, _ := ioutil.ReadAll(rc)
+	rc.Close()
+	expect(t, string(data), content)
+
+	//refused files remove the files already stored
+	req = uploadRequest(t, map[string]string{"a.png": content, "b.png": content, "c.png": content}, nil)
+	_, err = uploadContext(req).Upload(UploadConfig{Store: store, MaxFiles: 2})
+	expect(t, err, ErrTooManyFiles)
+	expect(t, store.Len(), 1)
+
+	req = uploadRequest(t, map[string]string{"notes.txt": "plain notes"}, nil)
+	_, err = uploadContext(req).Upload(UploadConfig{Store: store, AllowedTypes: []string{"image/png"}})
+	expect(t, err, ErrFileTypeNotAllowed)
+
+	//a renamed file is detected from its content
+	req = uploadRequest(t, map[string]string{"avatar.png": "<html><body>"}, nil)
+	_, err = uploadContext(req).Upload(UploadConfig{Store: store, AllowedTypes: []string{"image/png"}})
+	expect(t, err, ErrFileTypeNotAllowed)
+
+	//unidentified binary content is not typed from its extension
+	req = uploadRequest(t, map[string]string{"random.png": "\x00\x01\x02\xfe\xff\x10\x80\x03"}, nil)
+	_, err = uploadContext(req).Upload(UploadConfig{Store: store, AllowedTypes: []string{"image/png"}})
+	expect(t, err, ErrFileTypeNotAllowed)
+
+	req = uploadRequest(t, map[string]string{"random.png": "\x00\x01\x02\xfe\xff\x10\x80\x03"}, nil)
+	up, err = uploadContext(req).Upload(UploadConfig{Store: store})
+	if err != nil {
+		flux.FatalFailed(t, "Unable to upload: %s", err)
+	}
+	expect(t, up.Files[0].ContentType, "application/octet-stream")
+	store.Delete(up.Files[0].Key)
+
+	req = uploadRequest(t, map[string]string{"large.png": pngHeader + strings.Repeat("a", 100)}, nil)
+	_, err = uploadContext(req).Upload(UploadConfig{Store: store, MaxFileSize: 50})
+	expect(t, err, ErrFileTooLarge)
+	expect(t, store.Len(), 1)
+
+	req = uploadRequest(t, map[string]string{"large.png": pngHeader + strings.Repeat("a", 100)}, nil)
+	req.ContentLength = -1
+	_, err = uploadContext(req).Upload(UploadConfig{Store: store, MaxSize: 80})
+	expect(t, err, ErrBodyTooLarge)
+
+	req, _ = http.NewRequest("POST", "http://localhost:3000/upload", strings.NewReader("{}"))
+	req.Header.Set(ContentType, ContentJSON)
+	_, err = uploadContext(req).Upload(UploadConfig{Store: store})
+	expect(t, err, ErrNotMultipart)
+
+	expect(t, AsHTTPError(ErrFileTooLarge, false).Status, http.StatusRequestEntityTooLarge)
+	expect(t, AsHTTPError(ErrFileTypeNotAllowed, false).Status, http.StatusUnsupportedMediaType)
+}
+
+func TestDiskStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "relay-uploads")
+	if err != nil {
+		flux.FatalFailed(t, "Unable to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	store := NewDiskStore(dir)
+
+	req := uploadRequest(t, map[string]string{"notes.txt": "plain notes"}, nil)
+	up, err := uploadContext(req).Upload(UploadConfig{Store: store})
+	if err != nil {
+		flux.FatalFailed(t, "Unable to upload: %s", err)
+	}
+
+	file := up.Files[0]
+	expect(t, file.ContentType, "text/plain; charset=utf-8")
+
+	rc, err := store.Open(file.Key)
+	if err != nil {
+		flux.FatalFailed(t, "Unable to open upload: %s", err)
+	}
+
+	data, _ := ioutil.ReadAll(rc)
+	rc.Close()
+	expect(t, string(data), "plain notes")
+
+	if _, err := store.Open("../" + file.Key); err != ErrUploadNotFound {
+		flux.FatalFailed(t, "Expected keys outside the directory to be refused: %s", err)
+	}
+
+	expect(t, store.Delete(file.Key), nil)
+	expect(t, store.Delete(file.Key), ErrUploadNotFound)
+
+	//failed files leave nothing behind
+	req = uploadRequest(t, map[string]string{"large.txt": strings.Repeat("a", 100)}, nil)
+	_, err = uploadContext(req).Upload(UploadConfig{Store: store, MaxFileSize: 10})
+	expect(t, err, ErrFileTooLarge)
+
+	items, _ := ioutil.ReadDir(dir)
+	expect(t, len(items), 0)
+}
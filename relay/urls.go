@@ -0,0 +1,120 @@
+package relay
+
+import (
+	"fmt"
+	"html/template"
+	"net/url"
+	"strings"
+)
+
+// urls.go provides reverse url generation for named routes registered on a ChainRouter
+
+// ErrOddURLParams is returned when the parameters given to ChainRouter.URL are not key/value pairs
+var ErrOddURLParams = NewCustomError("ChainRouter.URL", "parameters must be given as key/value pairs")
+
+// URL builds the path for the named route using the supplied key/value pairs,
+// parameters not found in the route pattern are added as query values
+//
+//   router.NamedRule("user", "get", `/users/{id:[\d]+}`, nil)
+//   path, err := router.URL("user", "id", 20) // => /users/20
+func (r *ChainRouter) URL(name string, params ...interface{}) (string, error) {
+	if len(params)%2 != 0 {
+		return "", ErrOddURLParams
+	}
+
+	co := make(Collector, len(params)/2)
+	for i := 0; i < len(params); i += 2 {
+		co.Set(fmt.Sprint(params[i]), params[i+1])
+	}
+
+	return r.URLFor(name, co)
+}
+
+// URLFor builds the path for the named route using the values in the Collector,
+// values not found in the route pattern are added as query values
+func (r *ChainRouter) URLFor(name string, params Collector) (string, error) {
+	r.wg.RLock()
+	cr, ok := r.names[name]
+	r.wg.RUnlock()
+
+	if !ok {
+		return "", NewCustomError("ChainRouter.URL", fmt.Sprintf("no route with name %q", name))
+	}
+
+	return buildRouteURL(cr, params)
+}
+
+// FuncMap returns a template.FuncMap with the 'url' function for use in
+// templates rendered by the HTML encoder, it must be added before parsing
+//
+//   tl := template.Must(template.New("").Funcs(router.FuncMap()).Parse(`{{url "user" "id" 20}}`))
+func (r *ChainRouter) FuncMap() template.FuncMap {
+	return template.FuncMap{
+		"url": r.URL,
+	}
+}
+
+// buildRouteURL rebuilds the path of a route from its pattern tokens
+func buildRouteURL(cr *ChainRouta, params Collector) (string, error) {
+	var path []string
+	used := make(map[string]bool)
+
+	for _, tk := range cr.tokens {
+		if tk.kind == staticKind {
+			path = append(path, tk.text)
+			continue
+		}
+
+		val, ok := params[tk.name]
+		if !ok {
+			return "", NewCustomError("ChainRouter.URL", fmt.Sprintf("route %q requires parameter %q", cr.Name, tk.name))
+		}
+
+		used[tk.name] = true
+		value := fmt.Sprint(val)
+
+		switch tk.kind {
+		case catchAllKind:
+			var segments []string
+			for _, seg := range strings.Split(strings.TrimPrefix(value, "/"), "/") {
+				segments = append(segments, url.PathEscape(seg))
+			}
+
+			if tk.slash {
+				path = append(path, "/")
+			}
+
+			path = append(path, strings.Join(segments, "/"))
+			continue
+
+		case regexKind:
+			if !tk.rx.MatchString(value) {
+				return "", NewCustomError("ChainRouter.URL", fmt.Sprintf("parameter %q value %q does not match %s for route %q", tk.name, value, tk.rx, cr.Name))
+			}
+		}
+
+		if value == "" || strings.Contains(value, "/") {
+			return "", NewCustomError("ChainRouter.URL", fmt.Sprintf("parameter %q value %q is not a valid path segment for route %q", tk.name, value, cr.Name))
+		}
+
+		path = append(path, url.PathEscape(value))
+	}
+
+	built := strings.Join(path, "")
+	if built == "" {
+		built = "/"
+	}
+
+	query := make(url.Values)
+	for k, v := range params {
+		if !used[k] {
+			query.Add(k, fmt.Sprint(v))
+		}
+	}
+
+	if len(query) > 0 {
+		built += "?" + query.Encode()
+	}
+
+	return built, nil
+}
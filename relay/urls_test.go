@@ -0,0 +1,56 @@
+package relay
+
+import (
+	"bytes"
+	"html/template"
+	"testing"
+
+	"github.com/influx6/flux"
+)
+
+func TestRouterURL(t *testing.T) {
+	router := NewChainRouter(nil, nil)
+
+	router.NamedRule("user", "get", `/users/{id:[\d]+}`, nil)
+	router.NamedRule("post", "get", "/users/:user/posts/:post", nil)
+	router.NamedRule("assets", "get", "/assets/*", nil)
+
+	path, err := router.URL("user", "id", 20)
+	if err != nil {
+		flux.FatalFailed(t, "Unable to build url: %s", err)
+	}
+	expect(t, path, "/users/20")
+
+	path, err = router.URL("post", "user", "alex", "post", 3, "page", 2)
+	if err != nil {
+		flux.FatalFailed(t, "Unable to build url: %s", err)
+	}
+	expect(t, path, "/users/alex/posts/3?page=2")
+
+	path, err = router.URLFor("assets", Collector{"*": "css/main.css"})
+	if err != nil {
+		flux.FatalFailed(t, "Unable to build url: %s", err)
+	}
+	expect(t, path, "/assets/css/main.css")
+
+	if _, err := router.URL("user", "id", "alex"); err == nil {
+		flux.FatalFailed(t, "Expected regex validation error for 'id'")
+	}
+
+	if _, err := router.URL("post", "user", "alex"); err == nil {
+		flux.FatalFailed(t, "Expected missing parameter error for 'post'")
+	}
+
+	if _, err := router.URL("unknown"); err == nil {
+		flux.FatalFailed(t, "Expected error for unknown route name")
+	}
+
+	tl := template.Must(template.New("page").Funcs(router.FuncMap()).Parse(`<a href="{{url "user" "id" .}}">`))
+	html := HTMLRender(200, "page", 40, tl)
+
+	var buf bytes.Buffer
+	if _, err := HTMLEncoder.Encode(&buf, html); err != nil {
+		flux.FatalFailed(t, "Unable to render template: %s", err)
+	}
+	expect(t, buf.String(), `<a href="/users/40">`)
+}
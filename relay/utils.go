@@ -1,6 +1,7 @@
 package relay
 
 import (
+	"bytes"
 	"errors"
 	"io"
 	"net/http"
@@ -96,6 +97,64 @@ func setUpHeadings(r *Context) {
 var ErrNoBody = errors.New("Http Request Has no body")
 
 func loadData(r *Context) (*Message, error) {
+	msg, err := loadForms(r)
+	if msg != nil || err != nil {
+		return msg, err
+	}
+
+	body := newLimitedBody(r.Req.Body, r.bodyLimit())
+
+	var data []byte
+
+	if r.Req.ContentLength >= 0 {
+		data = make([]byte, r.Req.ContentLength)
+		if _, err := io.ReadFull(body, data); err != nil {
+			return nil, err
+		}
+	} else {
+		buf := bytes.NewBuffer(nil)
+		if _, err := buf.ReadFrom(body); err != nil {
+			return nil, err
+		}
+		data = buf.Bytes()
+	}
+
+	msg = &Message{
+		Method:      r.Req.Method,
+		Queries:     r.Req.URL.Query(),
+		MessageType: "body",
+		Payload:     data,
+	}
+
+	return msg, nil
+}
+
+func loadStream(r *Context) (*Message, error) {
+	msg, err := loadForms(r)
+	if msg != nil || err != nil {
+		return msg, err
+	}
+
+	msg = &Message{
+		Method:      r.Req.Method,
+		Queries:     r.Req.URL.Query(),
+		MessageType: "stream",
+		Body:        newLimitedBody(r.Req.Body, r.bodyLimit()),
+	}
+
+	return msg, nil
+}
+
+// loadForms decodes form, multipart and body-less requests, returning a nil
+// Message if the request body is not a form
+func loadForms(r *Context) (*Message, error) {
+	limit := r.bodyLimit()
+
+	//refuse bodies declaring a size above the limit before reading anything
+	if limit > 0 && r.Req.ContentLength > limit {
+		return nil, ErrBodyTooLarge
+	}
+
 	msg := Message{}
 	msg.Method = r.Req.Method
 
@@ -107,7 +166,14 @@ func loadData(r *Context) (*Message, error) {
 		muxcontent := strings.ToLower(strings.Join(content, ";"))
 
 		if strings.Index(muxcontent, "application/x-www-form-urlencode") != -1 {
+			if r.Req.Body != nil {
+				r.Req.Body = newLimitedBody(r.Req.Body, limit)
+			}
+
 			if err := r.Req.ParseForm(); err != nil {
+				if exceeded(r.Req.Body) {
+					return nil, ErrBodyTooLarge
+				}
 				return nil, err
 			}
 
@@ -120,7 +186,14 @@ func loadData(r *Context) (*Message, error) {
 		}
 
 		if strings.Index(muxcontent, "multipart/form-data") != -1 {
+			if r.Req.Body != nil {
+				r.Req.Body = newLimitedBody(r.Req.Body, limit)
+			}
+
 			if err := r.Req.ParseMultipartForm(32 << 20); err != nil {
+				if exceeded(r.Req.Body) {
+					return nil, ErrBodyTooLarge
+				}
 				return nil, err
 			}
 
@@ -141,17 +214,7 @@ func loadData(r *Context) (*Message, error) {
 		return &msg, nil
 	}
 
-	data := make([]byte, r.Req.ContentLength)
-	_, err := r.Req.Body.Read(data)
-
-	if err != nil && err != io.EOF {
-		return nil, err
-	}
-
-	msg.MessageType = "body"
-	msg.Payload = data
-
-	return &msg, nil
+	return nil, nil
 }
 
 func expect(t *testing.T, v, m interface{}) {
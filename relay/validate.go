@@ -0,0 +1,229 @@
+package relay
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// validate.go provides struct validation using 'validate' field tags, supporting
+// the 'required', 'min', 'max', 'len', 'email' and 'oneof' rules
+//
+//   type Signup struct {
+//     Email string `json:"email" validate:"required,email"`
+//     Name  string `json:"name" validate:"required,min=2,max=40"`
+//     Plan  string `json:"plan" validate:"oneof=free pro"`
+//   }
+
+var emailFormat = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)
+
+// FieldError provides the details of a struct field which failed binding or validation
+type FieldError struct {
+	Field   string `json:"field" xml:"field"`
+	Rule    string `json:"rule" xml:"rule"`
+	Param   string `json:"param,omitempty" xml:"param,omitempty"`
+	Message string `json:"message" xml:"message"`
+}
+
+// Error returns the error message
+func (f *FieldError) Error() string {
+	return fmt.Sprintf("%s: %s", f.Field, f.Message)
+}
+
+// ValidationErrors provides the list of FieldError returned by Validate and
+// Context.Bind, its meant to be rendered as a 422 response
+//
+//   if errs, ok := err.(ValidationErrors); ok {
+//     json := JSONRender(422, errs, false, false, false)
+//   }
+type ValidationErrors []*FieldError
+
+// Error returns the error messages joined together
+func (v ValidationErrors) Error() string {
+	var msgs []string
+	for _, fe := range v {
+		msgs = append(msgs, fe.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Validate checks the fields of the struct or pointer to struct using their
+// 'validate' tags and returns ValidationErrors if any rule fails
+func Validate(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return ErrNotStructPointer
+		}
+		rv = rv.Elem()
+	}
+
+	if rv.Kind() != reflect.Struct {
+		return ErrNotStructPointer
+	}
+
+	var errs ValidationErrors
+	validateStruct(rv, "", &errs)
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+// validateStruct checks the fields of the struct value, descending into nested structs
+func validateStruct(rv reflect.Value, prefix string, errs *ValidationErrors) {
+	rt := rv.Type()
+
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		fv := rv.Field(i)
+
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+
+		name := prefix + fieldName(field)
+
+		if rules := field.Tag.Get("validate"); rules != "" && rules != "-" {
+			for _, rule := range strings.Split(rules, ",") {
+				if fe := checkRule(fv, name, strings.TrimSpace(rule)); fe != nil {
+					*errs = append(*errs, fe)
+					break
+				}
+			}
+		}
+
+		nested := fv
+		if nested.Kind() == reflect.Ptr && !nested.IsNil() {
+			nested = nested.Elem()
+		}
+
+		if nested.Kind() == reflect.Struct && nested.Type() != timeType {
+			if field.Anonymous {
+				validateStruct(nested, prefix, errs)
+			} else {
+				validateStruct(nested, name+".", errs)
+			}
+		}
+	}
+}
+
+// fieldName returns the name of the field as given in its json, xml or form tags
+func fieldName(field reflect.StructField) string {
+	for _, tag := range []string{"json", "xml", "form"} {
+		name := strings.Split(field.Tag.Get(tag), ",")[0]
+		if name != "" && name != "-" {
+			return name
+		}
+	}
+	return field.Name
+}
+
+// checkRule validates the value against a single rule, returning a FieldError if it fails
+func checkRule(fv reflect.Value, name, rule string) *FieldError {
+	var param string
+	if ix := strings.Index(rule, "="); ix != -1 {
+		rule, param = rule[:ix], rule[ix+1:]
+	}
+
+	fail := func(msg string, args ...interface{}) *FieldError {
+		return &FieldError{Field: name, Rule: rule, Param: param, Message: fmt.Sprintf(msg, args...)}
+	}
+
+	if fv.Kind() == reflect.Ptr {
+		if fv.IsNil() {
+			if rule == "required" {
+				return fail("is required")
+			}
+			return nil
+		}
+		fv = fv.Elem()
+	}
+
+	switch rule {
+	case "required":
+		if isZeroValue(fv) {
+			return fail("is required")
+		}
+
+	case "min", "max", "len":
+		limit, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			return fail("has an invalid %s rule %q", rule, param)
+		}
+
+		size, isLength, ok := valueSize(fv)
+		if !ok {
+			return nil
+		}
+
+		unit := ""
+		if isLength {
+			unit = " in length"
+		}
+
+		switch {
+		case rule == "min" && size < limit:
+			return fail("must be at least %s%s", param, unit)
+		case rule == "max" && size > limit:
+			return fail("must be at most %s%s", param, unit)
+		case rule == "len" && size != limit:
+			return fail("must be exactly %s%s", param, unit)
+		}
+
+	case "email":
+		if fv.Kind() == reflect.String && fv.Len() > 0 && !emailFormat.MatchString(fv.String()) {
+			return fail("must be a valid email address")
+		}
+
+	case "oneof":
+		if isZeroValue(fv) {
+			return nil
+		}
+
+		value := fmt.Sprint(fv.Interface())
+		for _, opt := range strings.Fields(param) {
+			if opt == value {
+				return nil
+			}
+		}
+		return fail("must be one of [%s]", param)
+	}
+
+	return nil
+}
+
+// valueSize returns the length of strings and collections or the value of
+// numbers used by the 'min', 'max' and 'len' rules, whether the size is a length
+// and false for types without a size
+func valueSize(fv reflect.Value) (float64, bool, bool) {
+	switch fv.Kind() {
+	case reflect.String:
+		return float64(utf8.RuneCountInString(fv.String())), true, true
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return float64(fv.Len()), true, true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(fv.Int()), false, true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(fv.Uint()), false, true
+	case reflect.Float32, reflect.Float64:
+		return fv.Float(), false, true
+	}
+	return 0, false, false
+}
+
+// isZeroValue returns true/false if the value is the zero value of its type
+func isZeroValue(fv reflect.Value) bool {
+	switch fv.Kind() {
+	case reflect.Slice, reflect.Map:
+		return fv.Len() == 0
+	case reflect.Interface, reflect.Ptr:
+		return fv.IsNil()
+	}
+	return reflect.DeepEqual(fv.Interface(), reflect.Zero(fv.Type()).Interface())
+}